@@ -20,13 +20,16 @@ func main() {
 		log.Fatalf("Invalid configuration: %v", err)
 	}
 
+	scalerServer, err := server.NewExternalScalerServer(cfg.KeyPath, cfg)
+	if err != nil {
+		log.Fatalf("Failed to create scaler server: %v", err)
+	}
+
 	go func() {
-		http.HandleFunc(cfg.HealthPath, func(w http.ResponseWriter, r *http.Request) {
-			w.WriteHeader(http.StatusOK)
-			w.Write([]byte("OK"))
-		})
+		http.Handle(cfg.MetricsPath, scalerServer.Telemetry())
+		http.HandleFunc(cfg.HealthPath, scalerServer.ServeHealth)
 
-		log.Printf("Starting HTTP server for health checks on :%s", cfg.HTTPPort)
+		log.Printf("Starting HTTP server for health checks and metrics on :%s", cfg.HTTPPort)
 		if err := http.ListenAndServe(":"+cfg.HTTPPort, nil); err != nil {
 			log.Fatalf("Failed to start HTTP server: %v", err)
 		}
@@ -37,11 +40,6 @@ func main() {
 		log.Fatalf("Failed to listen: %v", err)
 	}
 
-	scalerServer, err := server.NewExternalScalerServer(cfg.KeyPath, cfg)
-	if err != nil {
-		log.Fatalf("Failed to create scaler server: %v", err)
-	}
-
 	grpcServer := grpc.NewServer()
 	protos.RegisterExternalScalerServer(grpcServer, scalerServer)
 
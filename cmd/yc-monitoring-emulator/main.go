@@ -0,0 +1,32 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"net/http"
+
+	"keda-external-scaler-yc-monitoring/internal/emulator"
+)
+
+func main() {
+	listen := flag.String("listen", "127.0.0.1:8090", "address to serve the emulated APIs on")
+	scenarioPath := flag.String("scenario", "", "path to the scenario file")
+	flag.Parse()
+
+	if *scenarioPath == "" {
+		log.Fatalf("-scenario is required")
+	}
+	scenario, err := emulator.LoadScenario(*scenarioPath)
+	if err != nil {
+		log.Fatalf("Failed to load scenario: %v", err)
+	}
+
+	e := emulator.New(scenario)
+	e.SetLogger(log.Printf)
+
+	log.Printf("Emulating Monitoring, IAM and WLIF token exchange on http://%s (token exchange at %s)",
+		*listen, emulator.WLIFTokenPath)
+	if err := http.ListenAndServe(*listen, e); err != nil {
+		log.Fatalf("Failed to serve: %v", err)
+	}
+}
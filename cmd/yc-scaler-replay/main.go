@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"keda-external-scaler-yc-monitoring/internal/replay"
+)
+
+// metadataFlags collects repeated -set key=value flags.
+type metadataFlags map[string]string
+
+func (m metadataFlags) String() string {
+	return fmt.Sprint(map[string]string(m))
+}
+
+func (m metadataFlags) Set(s string) error {
+	key, value, ok := strings.Cut(s, "=")
+	if !ok || key == "" {
+		return fmt.Errorf("expected key=value, got %q", s)
+	}
+	m[key] = value
+	return nil
+}
+
+func main() {
+	seriesPath := flag.String("series", "", "recorded series: Monitoring data/read JSON, or CSV with timestamp and value columns")
+	metadataPath := flag.String("metadata", "", "JSON object with the ScaledObject trigger metadata")
+	set := metadataFlags{}
+	flag.Var(set, "set", "trigger metadata key=value, overriding -metadata (repeatable)")
+	interval := flag.Duration("interval", 30*time.Second, "KEDA polling interval")
+	from := flag.String("from", "", "replay start (RFC 3339); defaults to the start of the recording")
+	to := flag.String("to", "", "replay end (RFC 3339); defaults to the end of the recording")
+	format := flag.String("format", "table", "output format: table or csv")
+
+	var hpa replay.HPAConfig
+	flag.Int64Var(&hpa.MinReplicas, "min-replicas", 1, "minReplicaCount; 0 enables scaling to zero")
+	flag.Int64Var(&hpa.MaxReplicas, "max-replicas", 100, "maxReplicaCount")
+	flag.Int64Var(&hpa.InitialReplicas, "initial-replicas", 0, "replicas at the start of the replay; defaults to min-replicas")
+	flag.Float64Var(&hpa.Tolerance, "tolerance", 0.1, "HPA tolerance")
+	flag.DurationVar(&hpa.ScaleUpStabilization, "scale-up-window", 0, "scale-up stabilization window")
+	flag.DurationVar(&hpa.ScaleDownStabilization, "scale-down-window", 5*time.Minute, "scale-down stabilization window")
+	flag.DurationVar(&hpa.CooldownPeriod, "cooldown", 5*time.Minute, "cooldownPeriod before scaling to zero")
+	flag.Parse()
+
+	if *seriesPath == "" {
+		log.Fatalf("-series is required")
+	}
+	if *format != "table" && *format != "csv" {
+		log.Fatalf("unsupported -format %q", *format)
+	}
+	// A zero window means the Kubernetes default in HPAConfig; on the
+	// command line 0 means no stabilization.
+	if hpa.ScaleDownStabilization == 0 {
+		hpa.ScaleDownStabilization = -1
+	}
+	if hpa.Tolerance == 0 {
+		hpa.Tolerance = -1
+	}
+
+	cfg := replay.Config{Metadata: map[string]string{}, Interval: *interval, HPA: hpa}
+	if *metadataPath != "" {
+		data, err := os.ReadFile(*metadataPath)
+		if err != nil {
+			log.Fatalf("Failed to read metadata: %v", err)
+		}
+		if err := json.Unmarshal(data, &cfg.Metadata); err != nil {
+			log.Fatalf("Invalid metadata in %s: %v", *metadataPath, err)
+		}
+	}
+	for k, v := range set {
+		cfg.Metadata[k] = v
+	}
+	for _, t := range []struct {
+		value string
+		dst   *time.Time
+	}{{*from, &cfg.From}, {*to, &cfg.To}} {
+		if t.value == "" {
+			continue
+		}
+		parsed, err := time.Parse(time.RFC3339, t.value)
+		if err != nil {
+			log.Fatalf("Invalid time %q: %v", t.value, err)
+		}
+		*t.dst = parsed
+	}
+
+	series, err := replay.LoadSeries(*seriesPath)
+	if err != nil {
+		log.Fatalf("Failed to load series: %v", err)
+	}
+
+	samples, err := replay.Run(context.Background(), replay.NewBackend(series), cfg)
+	if err != nil {
+		log.Fatalf("Replay failed: %v", err)
+	}
+
+	if *format == "csv" {
+		err = replay.WriteCSV(os.Stdout, samples)
+	} else {
+		err = replay.WriteTable(os.Stdout, samples)
+	}
+	if err != nil {
+		log.Fatalf("Failed to write output: %v", err)
+	}
+}
@@ -3,6 +3,7 @@ package auth
 import (
 	"context"
 	"fmt"
+	"net/http"
 
 	"keda-external-scaler-yc-monitoring/internal/config"
 )
@@ -11,12 +12,12 @@ type TokenProvider interface {
 	GetToken(context.Context) (string, error)
 }
 
-func NewTokenProvider(keyPath string, cfg *config.Config) (TokenProvider, error) {
+func NewTokenProvider(keyPath string, cfg *config.Config, httpClient *http.Client) (TokenProvider, error) {
 	switch cfg.AuthMethod {
 	case "authorizedKey":
-		return NewYandexAuth(keyPath, cfg)
+		return NewYandexAuth(keyPath, cfg, httpClient)
 	case "workloadIdentityFederation":
-		return NewWorkloadIdentityProvider(cfg), nil
+		return NewWorkloadIdentityProvider(cfg, httpClient), nil
 	default:
 		return nil, fmt.Errorf("unsupported authentication method %q", cfg.AuthMethod)
 	}
@@ -42,12 +42,12 @@ type WorkloadIdentityProvider struct {
 	tokenCache *tokenCacheEntry
 }
 
-func NewWorkloadIdentityProvider(cfg *config.Config) *WorkloadIdentityProvider {
+func NewWorkloadIdentityProvider(cfg *config.Config, httpClient *http.Client) *WorkloadIdentityProvider {
 	return &WorkloadIdentityProvider{
 		serviceAccountID: cfg.WLIFServiceAccountID,
 		exchangeURL:      cfg.WLIFTokenExchangeURL,
 		subjectTokenFile: cfg.WLIFSubjectTokenFile,
-		httpClient:       &http.Client{Timeout: cfg.APITimeout},
+		httpClient:       httpClient,
 		now:              time.Now,
 	}
 }
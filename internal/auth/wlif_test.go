@@ -81,8 +81,7 @@ func TestWorkloadIdentityProviderExchangeAndCache(t *testing.T) {
 	})}
 
 	now := time.Date(2026, time.July, 16, 10, 0, 0, 0, time.UTC)
-	provider := NewWorkloadIdentityProvider(testWLIFConfig(tokenPath))
-	provider.httpClient = client
+	provider := NewWorkloadIdentityProvider(testWLIFConfig(tokenPath), client)
 	provider.now = func() time.Time { return now }
 
 	for i := 0; i < 2; i++ {
@@ -113,8 +112,7 @@ func TestWorkloadIdentityProviderRefreshesAndRereadsSubjectToken(t *testing.T) {
 	})}
 
 	now := time.Date(2026, time.July, 16, 10, 0, 0, 0, time.UTC)
-	provider := NewWorkloadIdentityProvider(testWLIFConfig(tokenPath))
-	provider.httpClient = client
+	provider := NewWorkloadIdentityProvider(testWLIFConfig(tokenPath), client)
 	provider.now = func() time.Time { return now }
 
 	if _, err := provider.GetToken(context.Background()); err != nil {
@@ -143,8 +141,7 @@ func TestWorkloadIdentityProviderConcurrentRefresh(t *testing.T) {
 		time.Sleep(20 * time.Millisecond)
 		return response(http.StatusOK, `{"access_token":"iam-token","expires_in":3600}`), nil
 	})}
-	provider := NewWorkloadIdentityProvider(testWLIFConfig(tokenPath))
-	provider.httpClient = client
+	provider := NewWorkloadIdentityProvider(testWLIFConfig(tokenPath), client)
 
 	var group sync.WaitGroup
 	for i := 0; i < 10; i++ {
@@ -194,10 +191,11 @@ func TestWorkloadIdentityProviderErrors(t *testing.T) {
 				}
 			}
 			cfg := testWLIFConfig(tokenPath)
-			provider := NewWorkloadIdentityProvider(cfg)
+			client := &http.Client{Timeout: 10 * time.Millisecond}
 			if tt.transport != nil {
-				provider.httpClient = &http.Client{Transport: tt.transport, Timeout: 10 * time.Millisecond}
+				client.Transport = tt.transport
 			}
+			provider := NewWorkloadIdentityProvider(cfg, client)
 			_, err := provider.GetToken(context.Background())
 			if err == nil || !strings.Contains(err.Error(), tt.want) {
 				t.Fatalf("GetToken() error = %v, want substring %q", err, tt.want)
@@ -42,7 +42,7 @@ type IAMTokenResponse struct {
 	ExpiresAt time.Time `json:"expiresAt"`
 }
 
-func NewYandexAuth(keyPath string, cfg *config.Config) (*YandexAuth, error) {
+func NewYandexAuth(keyPath string, cfg *config.Config, httpClient *http.Client) (*YandexAuth, error) {
 	key, err := loadServiceAccountKey(keyPath)
 	if err != nil {
 		return nil, err
@@ -50,7 +50,7 @@ func NewYandexAuth(keyPath string, cfg *config.Config) (*YandexAuth, error) {
 	return &YandexAuth{
 		saKey:      key,
 		config:     cfg,
-		httpClient: &http.Client{Timeout: cfg.APITimeout},
+		httpClient: httpClient,
 	}, nil
 }
 
@@ -58,11 +58,10 @@ func TestAuthorizedKeyProviderRegression(t *testing.T) {
 	})}
 
 	cfg := &config.Config{IAMEndpoint: "https://iam.example.test", APITimeout: time.Second}
-	provider, err := NewYandexAuth(keyPath, cfg)
+	provider, err := NewYandexAuth(keyPath, cfg, client)
 	if err != nil {
 		t.Fatalf("NewYandexAuth() error = %v", err)
 	}
-	provider.httpClient = client
 
 	for i := 0; i < 2; i++ {
 		token, err := provider.GetToken(context.Background())
@@ -0,0 +1,205 @@
+package breaker
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+type State int
+
+const (
+	Closed State = iota
+	Open
+	HalfOpen
+)
+
+func (s State) String() string {
+	switch s {
+	case Closed:
+		return "closed"
+	case Open:
+		return "open"
+	case HalfOpen:
+		return "half-open"
+	default:
+		return "unknown"
+	}
+}
+
+var ErrOpen = errors.New("circuit breaker is open")
+
+// Breaker opens after threshold consecutive failures and rejects calls until
+// openTimeout has passed. It then lets a single probe through: success closes
+// it, failure opens it again.
+type Breaker struct {
+	mu          sync.Mutex
+	threshold   int
+	openTimeout time.Duration
+	now         func() time.Time
+
+	state    State
+	failures int
+	openedAt time.Time
+	probing  bool
+}
+
+// New returns a breaker; a threshold of zero or less disables it.
+func New(threshold int, openTimeout time.Duration) *Breaker {
+	return &Breaker{threshold: threshold, openTimeout: openTimeout, now: time.Now}
+}
+
+// Allow reports whether a call may proceed. A nil error from Allow must be
+// followed by Record or Release.
+func (b *Breaker) Allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.threshold <= 0 {
+		return nil
+	}
+
+	switch b.state {
+	case Open:
+		if b.now().Sub(b.openedAt) < b.openTimeout {
+			return ErrOpen
+		}
+		b.state = HalfOpen
+		b.probing = true
+		return nil
+	case HalfOpen:
+		if b.probing {
+			return ErrOpen
+		}
+		b.probing = true
+		return nil
+	default:
+		return nil
+	}
+}
+
+// Record reports the outcome of an allowed call and returns the state before
+// and after it.
+func (b *Breaker) Record(success bool) (State, State) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	from := b.state
+	if b.threshold <= 0 {
+		return from, from
+	}
+
+	b.probing = false
+	if success {
+		b.state = Closed
+		b.failures = 0
+		return from, b.state
+	}
+
+	b.failures++
+	if b.state == HalfOpen || b.failures >= b.threshold {
+		b.state = Open
+		b.openedAt = b.now()
+	}
+	return from, b.state
+}
+
+// Release ends an allowed call that has no outcome, e.g. one cancelled by the
+// caller. It frees the half-open probe slot without changing the state.
+func (b *Breaker) Release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.probing = false
+}
+
+func (b *Breaker) State() State {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == Open && b.now().Sub(b.openedAt) >= b.openTimeout {
+		return HalfOpen
+	}
+	return b.state
+}
+
+// idleTimeout is how long a breaker that is not open may go unused before a
+// Set drops it. Dropping a breaker only forgets failures below the threshold,
+// or an open timeout that has already passed.
+const idleTimeout = 10 * time.Minute
+
+// Set holds one breaker per key, created on first use. Breakers idle for
+// idleTimeout are dropped, so keys that are no longer used do not accumulate.
+type Set struct {
+	mu          sync.Mutex
+	threshold   int
+	openTimeout time.Duration
+	now         func() time.Time
+
+	breakers  map[string]*setEntry
+	lastSweep time.Time
+}
+
+type setEntry struct {
+	breaker  *Breaker
+	lastUsed time.Time
+}
+
+func NewSet(threshold int, openTimeout time.Duration) *Set {
+	return &Set{threshold: threshold, openTimeout: openTimeout, now: time.Now, breakers: map[string]*setEntry{}}
+}
+
+func (s *Set) Get(key string) *Breaker {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	s.sweepLocked(now)
+
+	e, ok := s.breakers[key]
+	if !ok {
+		b := New(s.threshold, s.openTimeout)
+		b.now = s.now
+		e = &setEntry{breaker: b}
+		s.breakers[key] = e
+	}
+	e.lastUsed = now
+	return e.breaker
+}
+
+// States returns the state of every breaker in the set without marking them
+// as used.
+func (s *Set) States() map[string]State {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	states := make(map[string]State, len(s.breakers))
+	for key, e := range s.breakers {
+		states[key] = e.breaker.State()
+	}
+	return states
+}
+
+func (s *Set) sweepLocked(now time.Time) {
+	if now.Sub(s.lastSweep) < idleTimeout {
+		return
+	}
+	s.lastSweep = now
+	for key, e := range s.breakers {
+		if now.Sub(e.lastUsed) >= idleTimeout && e.breaker.canDrop() {
+			delete(s.breakers, key)
+		}
+	}
+}
+
+// canDrop reports whether the breaker carries no state worth keeping: it is
+// not open and no probe is in flight.
+func (b *Breaker) canDrop() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.probing {
+		return false
+	}
+	return b.state != Open || b.now().Sub(b.openedAt) >= b.openTimeout
+}
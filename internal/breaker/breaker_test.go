@@ -0,0 +1,116 @@
+package breaker
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBreakerOpensAndProbes(t *testing.T) {
+	now := time.Date(2026, time.March, 1, 12, 0, 0, 0, time.UTC)
+	b := New(2, 30*time.Second)
+	b.now = func() time.Time { return now }
+
+	for i := 0; i < 2; i++ {
+		if err := b.Allow(); err != nil {
+			t.Fatalf("Allow() before threshold = %v", err)
+		}
+		b.Record(false)
+	}
+	if b.State() != Open {
+		t.Fatalf("state = %s, want open", b.State())
+	}
+	if err := b.Allow(); err != ErrOpen {
+		t.Fatalf("Allow() while open = %v, want ErrOpen", err)
+	}
+
+	now = now.Add(30 * time.Second)
+	if b.State() != HalfOpen {
+		t.Fatalf("state after timeout = %s, want half-open", b.State())
+	}
+	if err := b.Allow(); err != nil {
+		t.Fatalf("probe Allow() = %v", err)
+	}
+	if err := b.Allow(); err != ErrOpen {
+		t.Fatalf("second Allow() during probe = %v, want ErrOpen", err)
+	}
+
+	// A failed probe opens the breaker again for a full timeout.
+	if from, to := b.Record(false); from != HalfOpen || to != Open {
+		t.Fatalf("failed probe transition = %s -> %s", from, to)
+	}
+	now = now.Add(10 * time.Second)
+	if err := b.Allow(); err != ErrOpen {
+		t.Fatalf("Allow() after failed probe = %v, want ErrOpen", err)
+	}
+
+	now = now.Add(20 * time.Second)
+	if err := b.Allow(); err != nil {
+		t.Fatalf("second probe Allow() = %v", err)
+	}
+	if from, to := b.Record(true); from != HalfOpen || to != Closed {
+		t.Fatalf("successful probe transition = %s -> %s", from, to)
+	}
+}
+
+func TestBreakerSuccessResetsFailures(t *testing.T) {
+	b := New(2, time.Minute)
+	b.Record(false)
+	b.Record(true)
+	b.Record(false)
+	if b.State() != Closed {
+		t.Fatalf("state = %s, want closed after non-consecutive failures", b.State())
+	}
+}
+
+func TestBreakerDisabled(t *testing.T) {
+	b := New(0, time.Minute)
+	for i := 0; i < 10; i++ {
+		b.Record(false)
+	}
+	if err := b.Allow(); err != nil {
+		t.Fatalf("disabled breaker Allow() = %v", err)
+	}
+}
+
+func TestBreakerReleaseKeepsHalfOpen(t *testing.T) {
+	now := time.Date(2026, time.March, 1, 12, 0, 0, 0, time.UTC)
+	b := New(1, 30*time.Second)
+	b.now = func() time.Time { return now }
+
+	b.Record(false)
+	now = now.Add(30 * time.Second)
+	if err := b.Allow(); err != nil {
+		t.Fatalf("probe Allow() = %v", err)
+	}
+	b.Release()
+	if b.State() != HalfOpen {
+		t.Fatalf("state after released probe = %s, want half-open", b.State())
+	}
+	if err := b.Allow(); err != nil {
+		t.Fatalf("Allow() after released probe = %v, want a new probe", err)
+	}
+}
+
+func TestSetDropsIdleBreakers(t *testing.T) {
+	now := time.Date(2026, time.March, 1, 12, 0, 0, 0, time.UTC)
+	s := NewSet(1, time.Hour)
+	s.now = func() time.Time { return now }
+
+	s.Get("idle")
+	failing := s.Get("failing")
+	failing.Allow()
+	failing.Record(false)
+
+	now = now.Add(idleTimeout)
+	s.Get("active")
+	states := s.States()
+	if _, ok := states["idle"]; ok || states["failing"] != Open || states["active"] != Closed {
+		t.Fatalf("states after idle timeout = %v, want idle dropped and the open breaker kept", states)
+	}
+
+	now = now.Add(time.Hour)
+	s.Get("active")
+	if states := s.States(); len(states) != 1 || states["active"] != Closed {
+		t.Fatalf("states after open timeout = %v, want only the active breaker", states)
+	}
+}
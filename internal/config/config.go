@@ -3,6 +3,8 @@ package config
 import (
 	"fmt"
 	"os"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -15,13 +17,41 @@ type Config struct {
 	WLIFTokenExchangeURL string
 	WLIFSubjectTokenFile string
 
-	GRPCPort   string
-	HTTPPort   string
-	HealthPath string
+	GRPCPort    string
+	HTTPPort    string
+	HealthPath  string
+	MetricsPath string
 
 	KeyPath string
 
-	APITimeout time.Duration
+	APITimeout       time.Duration
+	MaxResponseBytes int64
+
+	HTTPDialTimeout         time.Duration
+	HTTPKeepAlive           time.Duration
+	HTTPTLSHandshakeTimeout time.Duration
+	HTTPIdleConnTimeout     time.Duration
+	HTTPMaxIdleConns        int
+	HTTPMaxIdleConnsPerHost int
+	HTTPEnableHTTP2         bool
+
+	RetryMaxAttempts    int
+	RetryInitialBackoff time.Duration
+	RetryMaxBackoff     time.Duration
+
+	BreakerFailureThreshold int
+	BreakerOpenTimeout      time.Duration
+
+	RateLimitQPS         float64
+	RateLimitBurst       int
+	MaxInFlight          int
+	FolderRateLimitQPS   float64
+	FolderRateLimitBurst int
+	FolderMaxInFlight    int
+
+	// TemplateEnvVars lists the environment variables that query and
+	// folder ID templates may refer to.
+	TemplateEnvVars []string
 }
 
 func LoadConfig() *Config {
@@ -34,13 +64,39 @@ func LoadConfig() *Config {
 		WLIFTokenExchangeURL: getEnv("WLIF_TOKEN_EXCHANGE_URL", "https://auth.yandex.cloud/oauth/token"),
 		WLIFSubjectTokenFile: getEnv("WLIF_SUBJECT_TOKEN_FILE", "/var/run/secrets/tokens/yc-wlif-token"),
 
-		GRPCPort:   getEnv("GRPC_PORT", "8080"),
-		HTTPPort:   getEnv("HTTP_PORT", "8081"),
-		HealthPath: getEnv("HEALTH_PATH", "/health"),
+		GRPCPort:    getEnv("GRPC_PORT", "8080"),
+		HTTPPort:    getEnv("HTTP_PORT", "8081"),
+		HealthPath:  getEnv("HEALTH_PATH", "/health"),
+		MetricsPath: getEnv("METRICS_PATH", "/metrics"),
 
 		KeyPath: getEnv("KEY_PATH", "/app/key.json"),
 
-		APITimeout: parseDurationWithDefault("API_TIMEOUT", 30*time.Second),
+		APITimeout:       parseDurationWithDefault("API_TIMEOUT", 30*time.Second),
+		MaxResponseBytes: int64(parseIntWithDefault("MAX_RESPONSE_SIZE", 32<<20)),
+
+		HTTPDialTimeout:         parseDurationWithDefault("HTTP_DIAL_TIMEOUT", 5*time.Second),
+		HTTPKeepAlive:           parseDurationWithDefault("HTTP_KEEP_ALIVE", 30*time.Second),
+		HTTPTLSHandshakeTimeout: parseDurationWithDefault("HTTP_TLS_HANDSHAKE_TIMEOUT", 5*time.Second),
+		HTTPIdleConnTimeout:     parseDurationWithDefault("HTTP_IDLE_CONN_TIMEOUT", 90*time.Second),
+		HTTPMaxIdleConns:        parseIntWithDefault("HTTP_MAX_IDLE_CONNS", 100),
+		HTTPMaxIdleConnsPerHost: parseIntWithDefault("HTTP_MAX_IDLE_CONNS_PER_HOST", 20),
+		HTTPEnableHTTP2:         parseBoolWithDefault("HTTP_ENABLE_HTTP2", true),
+
+		RetryMaxAttempts:    parseIntWithDefault("RETRY_MAX_ATTEMPTS", 3),
+		RetryInitialBackoff: parseDurationWithDefault("RETRY_INITIAL_BACKOFF", 200*time.Millisecond),
+		RetryMaxBackoff:     parseDurationWithDefault("RETRY_MAX_BACKOFF", 5*time.Second),
+
+		BreakerFailureThreshold: parseIntWithDefault("BREAKER_FAILURE_THRESHOLD", 5),
+		BreakerOpenTimeout:      parseDurationWithDefault("BREAKER_OPEN_TIMEOUT", 30*time.Second),
+
+		RateLimitQPS:         parseFloatWithDefault("RATE_LIMIT_QPS", 0),
+		RateLimitBurst:       parseIntWithDefault("RATE_LIMIT_BURST", 10),
+		MaxInFlight:          parseIntWithDefault("MAX_IN_FLIGHT", 0),
+		FolderRateLimitQPS:   parseFloatWithDefault("FOLDER_RATE_LIMIT_QPS", 0),
+		FolderRateLimitBurst: parseIntWithDefault("FOLDER_RATE_LIMIT_BURST", 5),
+		FolderMaxInFlight:    parseIntWithDefault("FOLDER_MAX_IN_FLIGHT", 0),
+
+		TemplateEnvVars: parseList("TEMPLATE_ENV_VARS"),
 	}
 }
 
@@ -60,10 +116,59 @@ func parseDurationWithDefault(key string, defaultValue time.Duration) time.Durat
 	return defaultValue
 }
 
+func parseIntWithDefault(key string, defaultValue int) int {
+	if value := os.Getenv(key); value != "" {
+		if n, err := strconv.Atoi(value); err == nil {
+			return n
+		}
+	}
+	return defaultValue
+}
+
+func parseBoolWithDefault(key string, defaultValue bool) bool {
+	if value := os.Getenv(key); value != "" {
+		if b, err := strconv.ParseBool(value); err == nil {
+			return b
+		}
+	}
+	return defaultValue
+}
+
+func parseFloatWithDefault(key string, defaultValue float64) float64 {
+	if value := os.Getenv(key); value != "" {
+		if f, err := strconv.ParseFloat(value, 64); err == nil {
+			return f
+		}
+	}
+	return defaultValue
+}
+
+func parseList(key string) []string {
+	var list []string
+	for _, item := range strings.Split(os.Getenv(key), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
 func (c *Config) Validate() error {
 	if c.MonitoringEndpoint == "" {
 		return fmt.Errorf("monitoring endpoint cannot be empty")
 	}
+	if c.MaxResponseBytes < 0 {
+		return fmt.Errorf("max response size cannot be negative")
+	}
+	if c.RetryMaxAttempts < 0 {
+		return fmt.Errorf("retry max attempts cannot be negative")
+	}
+	if c.RetryInitialBackoff < 0 || c.RetryMaxBackoff < 0 {
+		return fmt.Errorf("retry backoff cannot be negative")
+	}
+	if c.RateLimitQPS < 0 || c.FolderRateLimitQPS < 0 || c.MaxInFlight < 0 || c.FolderMaxInFlight < 0 {
+		return fmt.Errorf("rate and concurrency limits cannot be negative")
+	}
 
 	switch c.AuthMethod {
 	case "authorizedKey":
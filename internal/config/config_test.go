@@ -65,3 +65,28 @@ func TestValidateAuthenticationMethod(t *testing.T) {
 		})
 	}
 }
+
+func TestLoadConfigRetrySettings(t *testing.T) {
+	t.Setenv("RETRY_MAX_ATTEMPTS", "5")
+	t.Setenv("RETRY_MAX_BACKOFF", "2s")
+
+	cfg := LoadConfig()
+	if cfg.RetryMaxAttempts != 5 || cfg.RetryMaxBackoff != 2*time.Second || cfg.RetryInitialBackoff != 200*time.Millisecond {
+		t.Fatalf("retry settings = %d, %v, %v", cfg.RetryMaxAttempts, cfg.RetryInitialBackoff, cfg.RetryMaxBackoff)
+	}
+
+	cfg = validConfig()
+	cfg.RetryMaxAttempts = -1
+	if err := cfg.Validate(); err == nil {
+		t.Fatal("expected error for negative retry attempts")
+	}
+}
+
+func TestLoadConfigTemplateEnvVars(t *testing.T) {
+	t.Setenv("TEMPLATE_ENV_VARS", " CLUSTER, REGION ,,")
+
+	cfg := LoadConfig()
+	if len(cfg.TemplateEnvVars) != 2 || cfg.TemplateEnvVars[0] != "CLUSTER" || cfg.TemplateEnvVars[1] != "REGION" {
+		t.Fatalf("TemplateEnvVars = %q, want [CLUSTER REGION]", cfg.TemplateEnvVars)
+	}
+}
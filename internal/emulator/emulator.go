@@ -0,0 +1,377 @@
+package emulator
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	MonitoringPath = "/monitoring/v2/data/read"
+	IAMTokenPath   = "/iam/v1/tokens"
+	WLIFTokenPath  = "/oauth/token"
+
+	tokenExchangeGrantType = "urn:ietf:params:oauth:grant-type:token-exchange"
+)
+
+// grpcCodes maps HTTP statuses to the gRPC code in Monitoring and IAM error
+// bodies.
+var grpcCodes = map[int]int{
+	http.StatusBadRequest:          3,
+	http.StatusUnauthorized:        16,
+	http.StatusForbidden:           7,
+	http.StatusNotFound:            5,
+	http.StatusTooManyRequests:     8,
+	http.StatusInternalServerError: 13,
+	http.StatusServiceUnavailable:  14,
+	http.StatusGatewayTimeout:      4,
+}
+
+// Request is a call received by the emulator.
+type Request struct {
+	Endpoint string // "monitoring", "iam" or "wlif"
+	FolderID string
+	Query    string
+	Status   int
+}
+
+// Emulator serves the Monitoring data read, IAM token and WLIF token exchange
+// endpoints from a Scenario, all on one address.
+type Emulator struct {
+	scenario *Scenario
+	now      func() time.Time
+	logf     func(format string, args ...interface{})
+
+	mu         sync.Mutex
+	tokens     map[string]time.Time
+	issued     int
+	iamCalls   int
+	wlifCalls  int
+	queryCalls []int
+	requests   []Request
+}
+
+func New(scenario *Scenario) *Emulator {
+	return &Emulator{
+		scenario:   scenario,
+		now:        time.Now,
+		logf:       func(string, ...interface{}) {},
+		tokens:     map[string]time.Time{},
+		queryCalls: make([]int, len(scenario.Monitoring)),
+	}
+}
+
+// Start serves scenario on a local test server. Point the scaler's
+// MonitoringEndpoint and IAMEndpoint at its URL, and WLIFTokenExchangeURL at
+// the URL followed by WLIFTokenPath.
+func Start(scenario *Scenario) (*Emulator, *httptest.Server) {
+	e := New(scenario)
+	return e, httptest.NewServer(e)
+}
+
+// SetLogger logs every request with logf.
+func (e *Emulator) SetLogger(logf func(format string, args ...interface{})) {
+	e.logf = logf
+}
+
+// Requests returns the calls received so far, in order.
+func (e *Emulator) Requests() []Request {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]Request(nil), e.requests...)
+}
+
+func (e *Emulator) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeAPIError(w, http.StatusMethodNotAllowed, "", "method not allowed")
+		return
+	}
+
+	switch r.URL.Path {
+	case MonitoringPath:
+		e.serveRead(w, r)
+	case IAMTokenPath:
+		e.serveIAMToken(w, r)
+	case WLIFTokenPath:
+		e.serveTokenExchange(w, r)
+	default:
+		writeAPIError(w, http.StatusNotFound, "", "unknown path "+r.URL.Path)
+	}
+}
+
+func (e *Emulator) serveRead(w http.ResponseWriter, r *http.Request) {
+	folderID := r.URL.Query().Get("folderId")
+	var body struct {
+		Query    string `json:"query"`
+		FromTime string `json:"fromTime"`
+		ToTime   string `json:"toTime"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		e.record(Request{Endpoint: "monitoring", FolderID: folderID, Status: http.StatusBadRequest})
+		writeAPIError(w, http.StatusBadRequest, "", "invalid request body: "+err.Error())
+		return
+	}
+
+	req := Request{Endpoint: "monitoring", FolderID: folderID, Query: body.Query}
+	if !e.scenario.AcceptAnyToken {
+		if err := e.checkToken(r.Header.Get("Authorization")); err != nil {
+			req.Status = http.StatusUnauthorized
+			e.record(req)
+			writeAPIError(w, http.StatusUnauthorized, "", err.Error())
+			return
+		}
+	}
+
+	resp := e.nextRead(folderID, body.Query)
+	req.Status = resp.status()
+	e.record(req)
+	if !sleep(r.Context(), time.Duration(resp.Latency)) {
+		return
+	}
+	if resp.status() != http.StatusOK {
+		writeAPIError(w, resp.status(), resp.RetryAfter, resp.Message)
+		return
+	}
+
+	now := e.now().UTC()
+	from, err := time.Parse(time.RFC3339, body.FromTime)
+	if err != nil {
+		from = now.Add(-5 * time.Minute)
+	}
+	to, err := time.Parse(time.RFC3339, body.ToTime)
+	if err != nil {
+		to = now
+	}
+	writeJSON(w, http.StatusOK, readResponse(resp.Series, from, to))
+}
+
+func (e *Emulator) serveIAMToken(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		JWT string `json:"jwt"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil || strings.Count(body.JWT, ".") != 2 {
+		e.record(Request{Endpoint: "iam", Status: http.StatusBadRequest})
+		writeAPIError(w, http.StatusBadRequest, "", "request must contain a signed JWT")
+		return
+	}
+
+	e.mu.Lock()
+	resp := next(e.scenario.IAM.Responses, &e.iamCalls)
+	e.mu.Unlock()
+	e.record(Request{Endpoint: "iam", Status: resp.status()})
+	if !sleep(r.Context(), time.Duration(resp.Latency)) {
+		return
+	}
+	if resp.status() != http.StatusOK {
+		writeAPIError(w, resp.status(), resp.RetryAfter, resp.Message)
+		return
+	}
+
+	token, expiresAt := e.issue("iam", time.Duration(e.scenario.IAM.TTL), defaultIAMTokenTTL)
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"iamToken":  token,
+		"expiresAt": expiresAt.UTC().Format(time.RFC3339Nano),
+	})
+}
+
+func (e *Emulator) serveTokenExchange(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil || r.PostForm.Get("grant_type") != tokenExchangeGrantType || r.PostForm.Get("subject_token") == "" {
+		e.record(Request{Endpoint: "wlif", Status: http.StatusBadRequest})
+		writeJSON(w, http.StatusBadRequest, map[string]string{
+			"error":             "invalid_request",
+			"error_description": "expected a token exchange grant with a subject_token",
+		})
+		return
+	}
+
+	e.mu.Lock()
+	resp := next(e.scenario.WLIF.Responses, &e.wlifCalls)
+	e.mu.Unlock()
+	e.record(Request{Endpoint: "wlif", Status: resp.status()})
+	if !sleep(r.Context(), time.Duration(resp.Latency)) {
+		return
+	}
+	if resp.status() != http.StatusOK {
+		code := "temporarily_unavailable"
+		if resp.status() < http.StatusInternalServerError && resp.status() != http.StatusTooManyRequests {
+			code = "invalid_grant"
+		}
+		if resp.RetryAfter != "" {
+			w.Header().Set("Retry-After", resp.RetryAfter)
+		}
+		writeJSON(w, resp.status(), map[string]string{"error": code, "error_description": resp.Message})
+		return
+	}
+
+	ttl := time.Duration(e.scenario.WLIF.TTL)
+	if ttl <= 0 {
+		ttl = defaultWLIFTokenTTL
+	}
+	token, _ := e.issue("wlif", ttl, defaultWLIFTokenTTL)
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"access_token":      token,
+		"issued_token_type": "urn:ietf:params:oauth:token-type:access_token",
+		"token_type":        "Bearer",
+		"expires_in":        int64(ttl / time.Second),
+	})
+}
+
+func (e *Emulator) issue(kind string, ttl, defaultTTL time.Duration) (string, time.Time) {
+	if ttl <= 0 {
+		ttl = defaultTTL
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.issued++
+	token := fmt.Sprintf("emulator-%s-%d", kind, e.issued)
+	expiresAt := e.now().Add(ttl)
+	e.tokens[token] = expiresAt
+	return token, expiresAt
+}
+
+func (e *Emulator) checkToken(header string) error {
+	token := strings.TrimPrefix(header, "Bearer ")
+	if token == header || token == "" {
+		return fmt.Errorf("missing bearer token")
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	expiresAt, ok := e.tokens[token]
+	switch {
+	case !ok:
+		return fmt.Errorf("unknown token")
+	case !e.now().Before(expiresAt):
+		return fmt.Errorf("the token has expired")
+	}
+	return nil
+}
+
+func (e *Emulator) nextRead(folderID, query string) Response {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for i, q := range e.scenario.Monitoring {
+		if (q.FolderID == "" || q.FolderID == folderID) && (q.Query == "" || q.Query == query) {
+			return next(q.Responses, &e.queryCalls[i])
+		}
+	}
+	return Response{}
+}
+
+func (e *Emulator) record(req Request) {
+	e.mu.Lock()
+	e.requests = append(e.requests, req)
+	e.mu.Unlock()
+	if req.Endpoint == "monitoring" {
+		e.logf("%s folder=%q query=%q -> %d", req.Endpoint, req.FolderID, req.Query, req.Status)
+	} else {
+		e.logf("%s token -> %d", req.Endpoint, req.Status)
+	}
+}
+
+func next(responses []Response, calls *int) Response {
+	if len(responses) == 0 {
+		return Response{}
+	}
+	i := *calls
+	if i >= len(responses) {
+		i = len(responses) - 1
+	}
+	*calls++
+	return responses[i]
+}
+
+func (r Response) status() int {
+	if r.Status == 0 {
+		return http.StatusOK
+	}
+	return r.Status
+}
+
+func readResponse(series []Series, from, to time.Time) interface{} {
+	type timeseries struct {
+		Timestamps   []int64           `json:"timestamps"`
+		DoubleValues []json.RawMessage `json:"doubleValues"`
+	}
+	type metric struct {
+		Name       string            `json:"name"`
+		Labels     map[string]string `json:"labels,omitempty"`
+		Type       string            `json:"type"`
+		Timeseries timeseries        `json:"timeseries"`
+	}
+
+	metrics := make([]metric, 0, len(series))
+	for _, s := range series {
+		values := make([]json.RawMessage, len(s.Values))
+		for i, v := range s.Values {
+			values[i] = v
+			if len(v) == 0 {
+				values[i] = json.RawMessage("null")
+			}
+		}
+		metrics = append(metrics, metric{
+			Name:       s.Name,
+			Labels:     s.Labels,
+			Type:       "DGAUGE",
+			Timeseries: timeseries{Timestamps: s.timestamps(from, to), DoubleValues: values},
+		})
+	}
+	return map[string]interface{}{"metrics": metrics}
+}
+
+func (s Series) timestamps(from, to time.Time) []int64 {
+	if len(s.Timestamps) > 0 {
+		return s.Timestamps
+	}
+
+	n := len(s.Values)
+	step := time.Duration(s.Step)
+	if step <= 0 && n > 0 {
+		step = to.Sub(from) / time.Duration(n)
+	}
+	timestamps := make([]int64, n)
+	for i := range timestamps {
+		timestamps[i] = to.Add(-time.Duration(n-1-i) * step).UnixMilli()
+	}
+	return timestamps
+}
+
+func sleep(ctx context.Context, d time.Duration) bool {
+	if d <= 0 {
+		return true
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+func writeAPIError(w http.ResponseWriter, status int, retryAfter, message string) {
+	if message == "" {
+		message = http.StatusText(status)
+	}
+	code, ok := grpcCodes[status]
+	if !ok {
+		code = 2
+	}
+	if retryAfter != "" {
+		w.Header().Set("Retry-After", retryAfter)
+	}
+	writeJSON(w, status, map[string]interface{}{"code": code, "message": message, "details": []interface{}{}})
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,154 @@
+package emulator
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	protos "keda-external-scaler-yc-monitoring/gen/proto/externalscaler"
+	"keda-external-scaler-yc-monitoring/internal/config"
+	"keda-external-scaler-yc-monitoring/internal/server"
+)
+
+const testScenario = `{
+	"wlif": {"ttl": "1h", "responses": [{"status": 503, "message": "try later"}, {}]},
+	"monitoring": [
+		{
+			"query": "queue_depth",
+			"responses": [
+				{"status": 503, "retryAfter": "0"},
+				{"series": [
+					{"name": "queue", "labels": {"shard": "a"}, "values": [10, "NaN", 30]},
+					{"name": "queue", "labels": {"shard": "b"}, "values": [null, 50], "step": "30s"}
+				]}
+			]
+		},
+		{"query": "broken", "responses": [{"status": 400, "message": "unknown metric"}]}
+	]
+}`
+
+// newScaler runs a scaler with workload identity federation against the
+// emulator.
+func newScaler(t *testing.T, scenario string) (*server.ExternalScalerServer, *Emulator) {
+	t.Helper()
+	parsed, err := ParseScenario([]byte(scenario))
+	if err != nil {
+		t.Fatalf("ParseScenario() error = %v", err)
+	}
+	emulator, api := Start(parsed)
+	t.Cleanup(api.Close)
+
+	subjectToken := filepath.Join(t.TempDir(), "subject-token")
+	if err := os.WriteFile(subjectToken, []byte("subject"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	cfg := &config.Config{
+		AuthMethod:           "workloadIdentityFederation",
+		MonitoringEndpoint:   api.URL,
+		IAMEndpoint:          api.URL,
+		WLIFServiceAccountID: "service-account",
+		WLIFTokenExchangeURL: api.URL + WLIFTokenPath,
+		WLIFSubjectTokenFile: subjectToken,
+		APITimeout:           time.Second,
+		RetryMaxAttempts:     3,
+		RetryInitialBackoff:  time.Millisecond,
+	}
+	scaler, err := server.NewExternalScalerServer("", cfg)
+	if err != nil {
+		t.Fatalf("NewExternalScalerServer() error = %v", err)
+	}
+	return scaler, emulator
+}
+
+func metricsRequest(query string) *protos.GetMetricsRequest {
+	return &protos.GetMetricsRequest{
+		MetricName: "queue",
+		ScaledObjectRef: &protos.ScaledObjectRef{Name: "worker", Namespace: "jobs", ScalerMetadata: map[string]string{
+			"logLevel":          "none",
+			"query":             query,
+			"folderId":          "folder",
+			"aggregationMethod": "max",
+		}},
+	}
+}
+
+func TestScalerAgainstEmulator(t *testing.T) {
+	scaler, emulator := newScaler(t, testScenario)
+
+	// The first token exchange fails and is retried on the next call.
+	if _, err := scaler.GetMetrics(context.Background(), metricsRequest("queue_depth")); err == nil {
+		t.Fatal("GetMetrics() succeeded although the token exchange failed")
+	}
+
+	resp, err := scaler.GetMetrics(context.Background(), metricsRequest("queue_depth"))
+	if err != nil {
+		t.Fatalf("GetMetrics() error = %v", err)
+	}
+	if got := resp.MetricValues[0].MetricValueFloat; got != 50 {
+		t.Fatalf("GetMetrics() = %v, want 50", got)
+	}
+
+	_, err = scaler.GetMetrics(context.Background(), metricsRequest("broken"))
+	if status.Code(err) != codes.InvalidArgument || !strings.Contains(err.Error(), "unknown metric") {
+		t.Fatalf("GetMetrics() error = %v, want InvalidArgument with the API message", err)
+	}
+
+	var got []string
+	for _, req := range emulator.Requests() {
+		got = append(got, req.Endpoint+" "+req.Query+" "+http.StatusText(req.Status))
+	}
+	want := []string{
+		"wlif  Service Unavailable",
+		"wlif  OK",
+		"monitoring queue_depth Service Unavailable",
+		"monitoring queue_depth OK",
+		"monitoring broken Bad Request",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("requests:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestEmulatorRejectsExpiredTokens(t *testing.T) {
+	scenario, err := ParseScenario([]byte(`{"iam": {"ttl": "10m"}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2026, time.March, 1, 12, 0, 0, 0, time.UTC)
+	e := New(scenario)
+	e.now = func() time.Time { return now }
+
+	token, _ := e.issue("iam", time.Duration(scenario.IAM.TTL), defaultIAMTokenTTL)
+	if err := e.checkToken("Bearer " + token); err != nil {
+		t.Fatalf("checkToken() of a fresh token error = %v", err)
+	}
+	now = now.Add(10 * time.Minute)
+	if err := e.checkToken("Bearer " + token); err == nil || !strings.Contains(err.Error(), "expired") {
+		t.Fatalf("checkToken() of an expired token error = %v", err)
+	}
+	if err := e.checkToken("Bearer forged"); err == nil {
+		t.Fatal("checkToken() accepted a token the emulator did not issue")
+	}
+}
+
+func TestParseScenarioValidation(t *testing.T) {
+	tests := map[string]string{
+		"bad value":        `{"monitoring": [{"responses": [{"series": [{"values": ["oops"]}]}]}]}`,
+		"timestamp count":  `{"monitoring": [{"responses": [{"series": [{"values": [1, 2], "timestamps": [1]}]}]}]}`,
+		"unknown status":   `{"iam": {"responses": [{"status": 999}]}}`,
+		"bad duration":     `{"wlif": {"ttl": 60}}`,
+		"negative latency": `{"monitoring": [{"responses": [{"latency": "-1s"}]}]}`,
+	}
+	for name, scenario := range tests {
+		if _, err := ParseScenario([]byte(scenario)); err == nil {
+			t.Errorf("%s: ParseScenario() succeeded, want error", name)
+		}
+	}
+}
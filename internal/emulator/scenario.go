@@ -0,0 +1,151 @@
+package emulator
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"keda-external-scaler-yc-monitoring/internal/metrics"
+)
+
+const (
+	defaultIAMTokenTTL  = 12 * time.Hour
+	defaultWLIFTokenTTL = time.Hour
+)
+
+// Scenario describes what the emulated APIs answer.
+type Scenario struct {
+	IAM        TokenEndpoint `json:"iam"`
+	WLIF       TokenEndpoint `json:"wlif"`
+	Monitoring []Query       `json:"monitoring"`
+	// AcceptAnyToken lets Monitoring reads through with any bearer token
+	// instead of only unexpired tokens issued by the emulator.
+	AcceptAnyToken bool `json:"acceptAnyToken"`
+}
+
+type TokenEndpoint struct {
+	TTL       Duration   `json:"ttl"` // Lifetime of issued tokens
+	Responses []Response `json:"responses"`
+}
+
+// Query scripts the responses to Monitoring reads. Empty FolderID and Query
+// match any folder and any query; the first matching entry is used.
+type Query struct {
+	FolderID  string     `json:"folderId"`
+	Query     string     `json:"query"`
+	Responses []Response `json:"responses"`
+}
+
+// Response is one scripted answer. Responses are used in order and the last
+// one is repeated once they run out.
+type Response struct {
+	Status     int      `json:"status"` // Defaults to 200
+	Message    string   `json:"message"`
+	RetryAfter string   `json:"retryAfter"`
+	Latency    Duration `json:"latency"`
+	Series     []Series `json:"series"`
+}
+
+// Series is a Monitoring series. Values are written to the response as given:
+// numbers, "NaN", "Infinity", "-Infinity" or null.
+type Series struct {
+	Name   string            `json:"name"`
+	Labels map[string]string `json:"labels"`
+	Values []json.RawMessage `json:"values"`
+	// Timestamps are Unix milliseconds. When they are omitted the values are
+	// spaced Step apart, or evenly over the requested window, and the last
+	// one falls at the end of the window.
+	Timestamps []int64  `json:"timestamps"`
+	Step       Duration `json:"step"`
+}
+
+// Duration is a time.Duration written as a Go duration string.
+type Duration time.Duration
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("duration must be a string such as \"500ms\": %s", data)
+	}
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+	return nil
+}
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+func LoadScenario(path string) (*Scenario, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read scenario: %w", err)
+	}
+	scenario, err := ParseScenario(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return scenario, nil
+}
+
+func ParseScenario(data []byte) (*Scenario, error) {
+	var scenario Scenario
+	if err := json.Unmarshal(data, &scenario); err != nil {
+		return nil, fmt.Errorf("invalid scenario: %v", err)
+	}
+	if err := scenario.Validate(); err != nil {
+		return nil, err
+	}
+	return &scenario, nil
+}
+
+func (s *Scenario) Validate() error {
+	for name, endpoint := range map[string]TokenEndpoint{"iam": s.IAM, "wlif": s.WLIF} {
+		if endpoint.TTL < 0 {
+			return fmt.Errorf("%s.ttl must not be negative", name)
+		}
+		for i, resp := range endpoint.Responses {
+			if err := resp.validate(); err != nil {
+				return fmt.Errorf("%s.responses[%d]: %w", name, i, err)
+			}
+		}
+	}
+
+	for i, q := range s.Monitoring {
+		for j, resp := range q.Responses {
+			if err := resp.validate(); err != nil {
+				return fmt.Errorf("monitoring[%d].responses[%d]: %w", i, j, err)
+			}
+		}
+	}
+	return nil
+}
+
+func (r Response) validate() error {
+	if r.Status != 0 && http.StatusText(r.Status) == "" {
+		return fmt.Errorf("unknown status %d", r.Status)
+	}
+	if r.Latency < 0 {
+		return fmt.Errorf("latency must not be negative")
+	}
+	for i, series := range r.Series {
+		if len(series.Timestamps) > 0 && len(series.Timestamps) != len(series.Values) {
+			return fmt.Errorf("series[%d]: %d timestamps for %d values", i, len(series.Timestamps), len(series.Values))
+		}
+		if series.Step < 0 {
+			return fmt.Errorf("series[%d]: step must not be negative", i)
+		}
+		for j, raw := range series.Values {
+			var v metrics.Value
+			if err := v.UnmarshalJSON(raw); err != nil {
+				return fmt.Errorf("series[%d].values[%d]: %v", i, j, err)
+			}
+		}
+	}
+	return nil
+}
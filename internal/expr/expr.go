@@ -0,0 +1,318 @@
+package expr
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+type Expression struct {
+	source string
+	root   node
+}
+
+type node interface {
+	eval(vars map[string]float64) (float64, error)
+}
+
+type number float64
+
+type variable string
+
+type unary struct {
+	operand node
+}
+
+type binary struct {
+	op          byte
+	left, right node
+}
+
+type call struct {
+	name string
+	args []node
+}
+
+var functions = map[string]struct {
+	minArgs, maxArgs int
+	fn               func(args []float64) float64
+}{
+	"ceil":  {1, 1, func(a []float64) float64 { return math.Ceil(a[0]) }},
+	"floor": {1, 1, func(a []float64) float64 { return math.Floor(a[0]) }},
+	"round": {1, 1, func(a []float64) float64 { return math.Round(a[0]) }},
+	"abs":   {1, 1, func(a []float64) float64 { return math.Abs(a[0]) }},
+	"sqrt":  {1, 1, func(a []float64) float64 { return math.Sqrt(a[0]) }},
+	"log":   {1, 1, func(a []float64) float64 { return math.Log(a[0]) }},
+	"pow":   {2, 2, func(a []float64) float64 { return math.Pow(a[0], a[1]) }},
+	"min": {1, -1, func(a []float64) float64 {
+		m := a[0]
+		for _, v := range a[1:] {
+			m = math.Min(m, v)
+		}
+		return m
+	}},
+	"max": {1, -1, func(a []float64) float64 {
+		m := a[0]
+		for _, v := range a[1:] {
+			m = math.Max(m, v)
+		}
+		return m
+	}},
+}
+
+// Parse compiles source, accepting only the given variable names.
+func Parse(source string, variables ...string) (*Expression, error) {
+	p := &parser{input: source, variables: map[string]bool{}}
+	for _, v := range variables {
+		p.variables[v] = true
+	}
+
+	root, err := p.parseExpression()
+	if err != nil {
+		return nil, fmt.Errorf("invalid formula %q: %v", source, err)
+	}
+	p.skipSpace()
+	if p.pos < len(p.input) {
+		return nil, fmt.Errorf("invalid formula %q: unexpected %q at position %d", source, p.input[p.pos], p.pos)
+	}
+	return &Expression{source: source, root: root}, nil
+}
+
+func (e *Expression) Eval(vars map[string]float64) (float64, error) {
+	v, err := e.root.eval(vars)
+	if err != nil {
+		return 0, err
+	}
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return 0, fmt.Errorf("formula %q evaluated to %v", e.source, v)
+	}
+	return v, nil
+}
+
+func (e *Expression) String() string {
+	return e.source
+}
+
+func (n number) eval(map[string]float64) (float64, error) {
+	return float64(n), nil
+}
+
+func (v variable) eval(vars map[string]float64) (float64, error) {
+	value, ok := vars[string(v)]
+	if !ok {
+		return 0, fmt.Errorf("variable %q is not set", string(v))
+	}
+	return value, nil
+}
+
+func (u unary) eval(vars map[string]float64) (float64, error) {
+	v, err := u.operand.eval(vars)
+	return -v, err
+}
+
+func (b binary) eval(vars map[string]float64) (float64, error) {
+	left, err := b.left.eval(vars)
+	if err != nil {
+		return 0, err
+	}
+	right, err := b.right.eval(vars)
+	if err != nil {
+		return 0, err
+	}
+	switch b.op {
+	case '+':
+		return left + right, nil
+	case '-':
+		return left - right, nil
+	case '*':
+		return left * right, nil
+	case '/':
+		if right == 0 {
+			return 0, fmt.Errorf("division by zero")
+		}
+		return left / right, nil
+	case '%':
+		if right == 0 {
+			return 0, fmt.Errorf("division by zero")
+		}
+		return math.Mod(left, right), nil
+	default:
+		return 0, fmt.Errorf("unknown operator %q", b.op)
+	}
+}
+
+func (c call) eval(vars map[string]float64) (float64, error) {
+	args := make([]float64, len(c.args))
+	for i, arg := range c.args {
+		v, err := arg.eval(vars)
+		if err != nil {
+			return 0, err
+		}
+		args[i] = v
+	}
+	return functions[c.name].fn(args), nil
+}
+
+type parser struct {
+	input     string
+	pos       int
+	variables map[string]bool
+}
+
+func (p *parser) skipSpace() {
+	for p.pos < len(p.input) && unicode.IsSpace(rune(p.input[p.pos])) {
+		p.pos++
+	}
+}
+
+func (p *parser) peek() byte {
+	p.skipSpace()
+	if p.pos >= len(p.input) {
+		return 0
+	}
+	return p.input[p.pos]
+}
+
+// expression = term { ("+" | "-") term }
+func (p *parser) parseExpression() (node, error) {
+	left, err := p.parseTerm()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op := p.peek()
+		if op != '+' && op != '-' {
+			return left, nil
+		}
+		p.pos++
+		right, err := p.parseTerm()
+		if err != nil {
+			return nil, err
+		}
+		left = binary{op: op, left: left, right: right}
+	}
+}
+
+// term = factor { ("*" | "/" | "%") factor }
+func (p *parser) parseTerm() (node, error) {
+	left, err := p.parseFactor()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op := p.peek()
+		if op != '*' && op != '/' && op != '%' {
+			return left, nil
+		}
+		p.pos++
+		right, err := p.parseFactor()
+		if err != nil {
+			return nil, err
+		}
+		left = binary{op: op, left: left, right: right}
+	}
+}
+
+// factor = "-" factor | "+" factor | number | identifier [ "(" args ")" ] | "(" expression ")"
+func (p *parser) parseFactor() (node, error) {
+	switch c := p.peek(); {
+	case c == 0:
+		return nil, fmt.Errorf("unexpected end of formula")
+	case c == '-':
+		p.pos++
+		operand, err := p.parseFactor()
+		if err != nil {
+			return nil, err
+		}
+		return unary{operand: operand}, nil
+	case c == '+':
+		p.pos++
+		return p.parseFactor()
+	case c == '(':
+		p.pos++
+		inner, err := p.parseExpression()
+		if err != nil {
+			return nil, err
+		}
+		if p.peek() != ')' {
+			return nil, fmt.Errorf("missing ')' at position %d", p.pos)
+		}
+		p.pos++
+		return inner, nil
+	case c == '.' || (c >= '0' && c <= '9'):
+		return p.parseNumber()
+	case c == '_' || unicode.IsLetter(rune(c)):
+		return p.parseIdentifier()
+	default:
+		return nil, fmt.Errorf("unexpected %q at position %d", c, p.pos)
+	}
+}
+
+func (p *parser) parseNumber() (node, error) {
+	start := p.pos
+	for p.pos < len(p.input) {
+		c := p.input[p.pos]
+		isExponentSign := (c == '+' || c == '-') && p.pos > start &&
+			(p.input[p.pos-1] == 'e' || p.input[p.pos-1] == 'E')
+		if !(c == '.' || c == 'e' || c == 'E' || (c >= '0' && c <= '9') || isExponentSign) {
+			break
+		}
+		p.pos++
+	}
+	v, err := strconv.ParseFloat(p.input[start:p.pos], 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid number %q", p.input[start:p.pos])
+	}
+	return number(v), nil
+}
+
+func (p *parser) parseIdentifier() (node, error) {
+	start := p.pos
+	for p.pos < len(p.input) {
+		c := rune(p.input[p.pos])
+		if c != '_' && !unicode.IsLetter(c) && !unicode.IsDigit(c) {
+			break
+		}
+		p.pos++
+	}
+	name := p.input[start:p.pos]
+
+	if p.peek() != '(' {
+		if !p.variables[name] {
+			return nil, fmt.Errorf("unknown variable %q", name)
+		}
+		return variable(name), nil
+	}
+
+	fn, ok := functions[strings.ToLower(name)]
+	if !ok {
+		return nil, fmt.Errorf("unknown function %q", name)
+	}
+	p.pos++
+
+	var args []node
+	if p.peek() != ')' {
+		for {
+			arg, err := p.parseExpression()
+			if err != nil {
+				return nil, err
+			}
+			args = append(args, arg)
+			if p.peek() != ',' {
+				break
+			}
+			p.pos++
+		}
+	}
+	if p.peek() != ')' {
+		return nil, fmt.Errorf("missing ')' after arguments to %s", name)
+	}
+	p.pos++
+
+	if len(args) < fn.minArgs || (fn.maxArgs >= 0 && len(args) > fn.maxArgs) {
+		return nil, fmt.Errorf("wrong number of arguments to %s: %d", name, len(args))
+	}
+	return call{name: strings.ToLower(name), args: args}, nil
+}
@@ -0,0 +1,65 @@
+package expr
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestEval(t *testing.T) {
+	tests := []struct {
+		source string
+		want   float64
+	}{
+		{"ceil(value / 50)", 3},
+		{"max(2, min(value / 10, 8))", 8},
+		{"value * 2 + 1", 241},
+		{"-(value - 100) % 7", -6},
+		{"(value + 30) / 1.5e2", 1},
+		{"round(sqrt(value) )", 11},
+		{"pow(2, 3) - abs(-1)", 7},
+	}
+
+	for _, tt := range tests {
+		e, err := Parse(tt.source, "value")
+		if err != nil {
+			t.Fatalf("Parse(%q) error = %v", tt.source, err)
+		}
+		got, err := e.Eval(map[string]float64{"value": 120})
+		if err != nil {
+			t.Fatalf("Eval(%q) error = %v", tt.source, err)
+		}
+		if got != tt.want {
+			t.Fatalf("Eval(%q) = %v, want %v", tt.source, got, tt.want)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := map[string]string{
+		"":               "unexpected end",
+		"value +":        "unexpected end",
+		"queue / 2":      "unknown variable",
+		"exp(value)":     "unknown function",
+		"min()":          "wrong number of arguments",
+		"ceil(value":     "missing ')'",
+		"value 2":        "unexpected",
+		"value / 2) + 1": "unexpected",
+	}
+
+	for source, want := range tests {
+		if _, err := Parse(source, "value"); err == nil || !strings.Contains(err.Error(), want) {
+			t.Fatalf("Parse(%q) error = %v, want substring %q", source, err, want)
+		}
+	}
+}
+
+func TestEvalErrors(t *testing.T) {
+	e, _ := Parse("10 / value", "value")
+	if _, err := e.Eval(map[string]float64{"value": 0}); err == nil {
+		t.Fatalf("Eval() division by zero succeeded, want error")
+	}
+	e, _ = Parse("log(value)", "value")
+	if _, err := e.Eval(map[string]float64{"value": -1}); err == nil {
+		t.Fatalf("Eval() of NaN result succeeded, want error")
+	}
+}
@@ -0,0 +1,40 @@
+package httpclient
+
+import (
+	"crypto/tls"
+	"net"
+	"net/http"
+	"time"
+
+	"keda-external-scaler-yc-monitoring/internal/config"
+)
+
+// New returns a client with a pooled transport tuned from cfg. A single
+// client is shared by the Monitoring and IAM callers so that connections are
+// reused across ScaledObjects and token refreshes.
+func New(cfg *config.Config) *http.Client {
+	dialer := &net.Dialer{
+		Timeout:   cfg.HTTPDialTimeout,
+		KeepAlive: cfg.HTTPKeepAlive,
+	}
+
+	transport := &http.Transport{
+		Proxy:                 http.ProxyFromEnvironment,
+		DialContext:           dialer.DialContext,
+		ForceAttemptHTTP2:     cfg.HTTPEnableHTTP2,
+		MaxIdleConns:          cfg.HTTPMaxIdleConns,
+		MaxIdleConnsPerHost:   cfg.HTTPMaxIdleConnsPerHost,
+		IdleConnTimeout:       cfg.HTTPIdleConnTimeout,
+		TLSHandshakeTimeout:   cfg.HTTPTLSHandshakeTimeout,
+		ExpectContinueTimeout: time.Second,
+	}
+	if !cfg.HTTPEnableHTTP2 {
+		// A non-nil, empty TLSNextProto map disables HTTP/2 negotiation.
+		transport.TLSNextProto = map[string]func(string, *tls.Conn) http.RoundTripper{}
+	}
+
+	return &http.Client{
+		Transport: transport,
+		Timeout:   cfg.APITimeout,
+	}
+}
@@ -0,0 +1,38 @@
+package httpclient
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"keda-external-scaler-yc-monitoring/internal/config"
+)
+
+func TestNewConfiguresTransport(t *testing.T) {
+	cfg := &config.Config{
+		APITimeout:              10 * time.Second,
+		HTTPDialTimeout:         2 * time.Second,
+		HTTPIdleConnTimeout:     time.Minute,
+		HTTPMaxIdleConns:        50,
+		HTTPMaxIdleConnsPerHost: 5,
+		HTTPEnableHTTP2:         true,
+	}
+
+	client := New(cfg)
+	transport := client.Transport.(*http.Transport)
+	if client.Timeout != 10*time.Second {
+		t.Errorf("Timeout = %v", client.Timeout)
+	}
+	if transport.MaxIdleConns != 50 || transport.MaxIdleConnsPerHost != 5 || transport.IdleConnTimeout != time.Minute {
+		t.Errorf("idle pool = %d/%d/%v", transport.MaxIdleConns, transport.MaxIdleConnsPerHost, transport.IdleConnTimeout)
+	}
+	if !transport.ForceAttemptHTTP2 || transport.TLSNextProto != nil {
+		t.Errorf("HTTP/2 should be enabled")
+	}
+
+	cfg.HTTPEnableHTTP2 = false
+	transport = New(cfg).Transport.(*http.Transport)
+	if transport.ForceAttemptHTTP2 || transport.TLSNextProto == nil {
+		t.Errorf("HTTP/2 should be disabled")
+	}
+}
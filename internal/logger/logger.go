@@ -85,6 +85,10 @@ func (l *Logger) Error(format string, args ...interface{}) {
 	}
 }
 
+func (l *Logger) DebugEnabled() bool {
+	return l.level >= LogLevelDebug
+}
+
 func (l *Logger) LogMetrics(metrics interface{}) {
 	if l.logMetrics && l.level >= LogLevelDebug {
 		log.Printf("[METRICS] [%s] Raw metrics: %+v", l.scalerName, metrics)
@@ -133,10 +137,10 @@ func (l *Logger) LogParsedMetrics(metrics interface{}) {
 	}
 }
 
-func (l *Logger) LogClientProcessing(totalCount, nanCount, validCount int, allValues []float64, nanStrategy interface{}) {
+func (l *Logger) LogClientProcessing(totalCount, nanCount, infCount, missingCount, rejectedCount, validCount int, allValues []float64, nanStrategy interface{}) {
 	if l.level >= LogLevelDebug {
-		log.Printf("[CLIENT-PROCESSING] [%s] Data summary: total=%d, NaN=%d, valid=%d, nanStrategy=%v",
-			l.scalerName, totalCount, nanCount, validCount, nanStrategy)
+		log.Printf("[CLIENT-PROCESSING] [%s] Data summary: total=%d, NaN=%d, Inf=%d, missing=%d, outliers=%d, valid=%d, nanStrategy=%v",
+			l.scalerName, totalCount, nanCount, infCount, missingCount, rejectedCount, validCount, nanStrategy)
 		log.Printf("[CLIENT-PROCESSING] [%s] All extracted values: %v", l.scalerName, allValues)
 
 		if len(allValues) > 0 {
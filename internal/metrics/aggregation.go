@@ -2,9 +2,29 @@ package metrics
 
 import (
 	"fmt"
-	"strconv"
+	"sort"
+	"time"
 )
 
+type Point struct {
+	Timestamp int64 // Unix milliseconds
+	Value     float64
+}
+
+type Series struct {
+	Name   string
+	Labels map[string]string
+	Points []Point
+}
+
+func seriesPoints(series []Series) [][]Point {
+	points := make([][]Point, len(series))
+	for i, s := range series {
+		points[i] = s.Points
+	}
+	return points
+}
+
 func Aggregate(values []float64, method AggregationMethod) (float64, error) {
 	if len(values) == 0 {
 		return 0, fmt.Errorf("no values to aggregate")
@@ -46,43 +66,178 @@ func Aggregate(values []float64, method AggregationMethod) (float64, error) {
 	case AggregationLast:
 		return values[len(values)-1], nil
 
+	case AggregationLatest, AggregationEarliest, AggregationTWA:
+		return 0, fmt.Errorf("aggregation method %s requires timestamps", method)
+
 	default:
 		return 0, fmt.Errorf("unknown aggregation method: %s", method)
 	}
 }
 
-func ExtractValidValues(values []interface{}, strategy NaNStrategy, lastValid *float64) ([]float64, *float64) {
-	var result []float64
-
-	for _, val := range values {
-		switch v := val.(type) {
-		case float64:
-			result = append(result, v)
-			lastValid = &v
-		case int64:
-			floatVal := float64(v)
-			result = append(result, floatVal)
-			lastValid = &floatVal
-		case string:
-			if v == "NaN" {
-				switch strategy {
-				case NaNStrategyZero:
-					result = append(result, 0.0)
-				case NaNStrategyLastValid:
-					if lastValid != nil {
-						result = append(result, *lastValid)
-					}
-				case NaNStrategySkip:
-				case NaNStrategyError:
-				}
-			} else {
-				if f, err := strconv.ParseFloat(v, 64); err == nil {
-					result = append(result, f)
-					lastValid = &f
-				}
+// AggregatePoints aggregates timestamped values. latest, earliest and twa use
+// the timestamps; every other method aggregates the values in order.
+func AggregatePoints(points []Point, method AggregationMethod) (float64, error) {
+	if len(points) == 0 {
+		return 0, fmt.Errorf("no values to aggregate")
+	}
+
+	switch method {
+	case AggregationLatest:
+		latest := points[0]
+		for _, p := range points[1:] {
+			if p.Timestamp >= latest.Timestamp {
+				latest = p
+			}
+		}
+		return latest.Value, nil
+
+	case AggregationEarliest:
+		earliest := points[0]
+		for _, p := range points[1:] {
+			if p.Timestamp < earliest.Timestamp {
+				earliest = p
+			}
+		}
+		return earliest.Value, nil
+
+	case AggregationTWA:
+		return timeWeightedAverage(points), nil
+
+	default:
+		return Aggregate(pointValues(points), method)
+	}
+}
+
+// timeWeightedAverage integrates the series with the trapezoidal rule and
+// divides by its time span. Points sharing the earliest and latest timestamp
+// fall back to a plain average.
+func timeWeightedAverage(points []Point) float64 {
+	sorted := sortedPoints(points)
+	first, last := sorted[0].Timestamp, sorted[len(sorted)-1].Timestamp
+	if first == last {
+		sum := 0.0
+		for _, p := range sorted {
+			sum += p.Value
+		}
+		return sum / float64(len(sorted))
+	}
+
+	area := 0.0
+	for i := 1; i < len(sorted); i++ {
+		dt := float64(sorted[i].Timestamp - sorted[i-1].Timestamp)
+		area += dt * (sorted[i].Value + sorted[i-1].Value) / 2
+	}
+	return area / float64(last-first)
+}
+
+func latestTimestamp(points []Point) int64 {
+	latest := points[0].Timestamp
+	for _, p := range points[1:] {
+		if p.Timestamp > latest {
+			latest = p.Timestamp
+		}
+	}
+	return latest
+}
+
+// ExtractValidPoints pairs a single series' values with their timestamps,
+// replacing NaN and missing points according to strategy and infinite points
+// according to infStrategy. Gaps are only filled from the same series.
+func ExtractValidPoints(timestamps []int64, values []Value, strategy, infStrategy NaNStrategy, maxAge time.Duration) ([]Point, ValueCounts) {
+	var counts ValueCounts
+
+	n := len(values)
+	if len(timestamps) < n {
+		n = len(timestamps)
+	}
+
+	// prev[i] and next[i] hold the nearest finite index before and after i, or -1.
+	prev := make([]int, n)
+	next := make([]int, n)
+	last := -1
+	for i := 0; i < n; i++ {
+		prev[i] = last
+		if values[i].Kind == ValueFinite {
+			last = i
+		}
+	}
+	last = -1
+	for i := n - 1; i >= 0; i-- {
+		next[i] = last
+		if values[i].Kind == ValueFinite {
+			last = i
+		}
+	}
+
+	result := make([]Point, 0, n)
+	for i := 0; i < n; i++ {
+		counts.Total++
+		ts := timestamps[i]
+
+		replacement := strategy
+		switch values[i].Kind {
+		case ValueFinite:
+			counts.Finite++
+			result = append(result, Point{Timestamp: ts, Value: values[i].Float})
+			continue
+		case ValueNaN:
+			counts.NaN++
+		case ValueMissing:
+			counts.Missing++
+		case ValuePosInf, ValueNegInf:
+			counts.Inf++
+			replacement = infStrategy
+		}
+
+		before, after := prev[i], next[i]
+		switch replacement {
+		case NaNStrategyZero:
+			result = append(result, Point{Timestamp: ts, Value: 0})
+		case NaNStrategyLastValid:
+			if before >= 0 {
+				result = append(result, Point{Timestamp: ts, Value: values[before].Float})
+			}
+		case NaNStrategyLastValidWithin:
+			if before >= 0 && time.Duration(ts-timestamps[before])*time.Millisecond <= maxAge {
+				result = append(result, Point{Timestamp: ts, Value: values[before].Float})
 			}
+		case NaNStrategyNextValid:
+			if after >= 0 {
+				result = append(result, Point{Timestamp: ts, Value: values[after].Float})
+			}
+		case NaNStrategyInterpolate:
+			if before >= 0 && after >= 0 {
+				result = append(result, Point{Timestamp: ts, Value: interpolate(
+					timestamps[before], values[before].Float, timestamps[after], values[after].Float, ts)})
+			}
+		case NaNStrategySkip:
+		case NaNStrategyError:
 		}
 	}
 
-	return result, lastValid
+	return result, counts
+}
+
+func interpolate(t0 int64, v0 float64, t1 int64, v1 float64, t int64) float64 {
+	if t1 == t0 {
+		return v0
+	}
+	return v0 + (v1-v0)*float64(t-t0)/float64(t1-t0)
+}
+
+func pointValues(points []Point) []float64 {
+	values := make([]float64, len(points))
+	for i, p := range points {
+		values[i] = p.Value
+	}
+	return values
+}
+
+func sortedPoints(points []Point) []Point {
+	sorted := make([]Point, len(points))
+	copy(sorted, points)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Timestamp < sorted[j].Timestamp
+	})
+	return sorted
 }
@@ -0,0 +1,121 @@
+package metrics
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// AlignSeries combines several series into one by aggregating across series
+// at each aligned timestamp. With a grid, timestamps are bucketed to the start
+// of their grid interval; otherwise timestamps within tolerance of the first
+// timestamp of a bucket share that bucket. A series contributes its latest
+// point in each bucket. The result is ordered by timestamp.
+func AlignSeries(series [][]Point, method AggregationMethod, tolerance, grid time.Duration) ([]Point, error) {
+	type entry struct {
+		series int
+		point  Point
+	}
+
+	var entries []entry
+	for i, points := range series {
+		for _, p := range points {
+			entries = append(entries, entry{series: i, point: p})
+		}
+	}
+	if len(entries) == 0 {
+		return nil, nil
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].point.Timestamp < entries[j].point.Timestamp
+	})
+
+	gridMillis := grid.Milliseconds()
+	toleranceMillis := tolerance.Milliseconds()
+
+	var result []Point
+	latest := map[int]Point{}
+	bucketStart := int64(0)
+
+	flush := func() error {
+		if len(latest) == 0 {
+			return nil
+		}
+		points := make([]Point, 0, len(latest))
+		for i := range series {
+			if p, ok := latest[i]; ok {
+				points = append(points, p)
+			}
+		}
+		value, err := AggregatePoints(points, method)
+		if err != nil {
+			return err
+		}
+		result = append(result, Point{Timestamp: bucketStart, Value: value})
+		latest = map[int]Point{}
+		return nil
+	}
+
+	for i, e := range entries {
+		key := e.point.Timestamp
+		if gridMillis > 0 {
+			key = floorDiv(key, gridMillis) * gridMillis
+		}
+
+		newBucket := i == 0
+		if !newBucket {
+			if gridMillis > 0 {
+				newBucket = key != bucketStart
+			} else {
+				newBucket = key-bucketStart > toleranceMillis
+			}
+		}
+
+		if newBucket {
+			if err := flush(); err != nil {
+				return nil, err
+			}
+			bucketStart = key
+		}
+		latest[e.series] = e.point
+	}
+
+	if err := flush(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func floorDiv(a, b int64) int64 {
+	q := a / b
+	if a%b != 0 && (a < 0) != (b < 0) {
+		q--
+	}
+	return q
+}
+
+// ParseAlignmentTolerance parses how far apart timestamps from different
+// series may be and still be aggregated together.
+func ParseAlignmentTolerance(s string) time.Duration {
+	if d, err := time.ParseDuration(strings.TrimSpace(s)); err == nil && d >= 0 {
+		return d
+	}
+	return 0
+}
+
+func alignmentGrid(options QueryOptions) time.Duration {
+	mode := options.Downsampling.Mode
+	if (mode == DownsamplingGridInterval || mode == DownsamplingAuto) && options.Downsampling.GridInterval > 0 {
+		return time.Duration(options.Downsampling.GridInterval) * time.Millisecond
+	}
+	return 0
+}
+
+func describeAlignment(tolerance, grid time.Duration) string {
+	if grid > 0 {
+		return fmt.Sprintf("grid %v", grid)
+	}
+	return fmt.Sprintf("tolerance %v", tolerance)
+}
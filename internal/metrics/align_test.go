@@ -0,0 +1,114 @@
+package metrics
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestAlignSeries(t *testing.T) {
+	zoneA := []Point{{0, 10}, {60000, 20}, {120000, 30}}
+	zoneB := []Point{{1000, 5}, {61000, 50}}
+	zoneC := []Point{{119000, 1}}
+
+	tests := []struct {
+		name      string
+		tolerance time.Duration
+		grid      time.Duration
+		want      []Point
+	}{
+		{name: "exact", want: []Point{{0, 10}, {1000, 5}, {60000, 20}, {61000, 50}, {119000, 1}, {120000, 30}}},
+		{name: "tolerance", tolerance: 2 * time.Second, want: []Point{{0, 15}, {60000, 70}, {119000, 31}}},
+		{name: "grid", grid: time.Minute, want: []Point{{0, 15}, {60000, 71}, {120000, 30}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := AlignSeries([][]Point{zoneA, zoneB, zoneC}, AggregationSum, tt.tolerance, tt.grid)
+			if err != nil {
+				t.Fatalf("AlignSeries() error = %v", err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("AlignSeries() = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("AlignSeries() = %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestQueryMetricPointwiseAggregation(t *testing.T) {
+	// Zone b is decoded last but its latest sample is older than zone a's.
+	client := newTestClient(t, respondWith(`{"metrics":[
+		{"name":"a","timeseries":{"timestamps":[60000,120000],"doubleValues":[10,40]}},
+		{"name":"b","timeseries":{"timestamps":[60000,120000],"doubleValues":[30,5]}}
+	]}`))
+
+	got, err := client.QueryMetric(context.Background(), QueryOptions{
+		Query:                  "q",
+		FolderID:               "folder",
+		NaNStrategy:            NaNStrategySkip,
+		CrossSeriesAggregation: AggregationSum,
+		AggregationMethod:      AggregationMax,
+	}, testLogger())
+	if err != nil {
+		t.Fatalf("QueryMetric() error = %v", err)
+	}
+	if got != 45 {
+		t.Fatalf("QueryMetric() = %v, want max over time of per-minute sums = 45", got)
+	}
+}
+
+func TestAggregatePointsTimestampAware(t *testing.T) {
+	// Out of order on purpose; a step from 0 to 100 held for the last third.
+	points := []Point{{120000, 100}, {0, 0}, {90000, 100}, {60000, 0}}
+
+	tests := []struct {
+		method AggregationMethod
+		want   float64
+	}{
+		{AggregationLatest, 100},
+		{AggregationEarliest, 0},
+		{AggregationLast, 0},
+		// Trapezoids: 0 over [0,60s], 50 avg over [60s,90s], 100 over [90s,120s].
+		{AggregationTWA, (0*60 + 50*30 + 100*30) / 120.0},
+		{AggregationAvg, 50},
+	}
+
+	for _, tt := range tests {
+		got, err := AggregatePoints(points, tt.method)
+		if err != nil {
+			t.Fatalf("AggregatePoints(%s) error = %v", tt.method, err)
+		}
+		if got != tt.want {
+			t.Fatalf("AggregatePoints(%s) = %v, want %v", tt.method, got, tt.want)
+		}
+	}
+
+	if got, _ := AggregatePoints([]Point{{5, 2}, {5, 4}}, AggregationTWA); got != 3 {
+		t.Fatalf("AggregatePoints(twa) over a single timestamp = %v, want 3", got)
+	}
+}
+
+func TestQueryMetricLatestAcrossSeries(t *testing.T) {
+	client := newTestClient(t, respondWith(`{"metrics":[
+		{"name":"a","timeseries":{"timestamps":[60000,180000],"doubleValues":[1,7]}},
+		{"name":"b","timeseries":{"timestamps":[60000,120000],"doubleValues":[2,3]}}
+	]}`))
+
+	got, err := client.QueryMetric(context.Background(), QueryOptions{
+		Query:             "q",
+		FolderID:          "folder",
+		NaNStrategy:       NaNStrategySkip,
+		AggregationMethod: AggregationLatest,
+	}, testLogger())
+	if err != nil {
+		t.Fatalf("QueryMetric() error = %v", err)
+	}
+	if got != 7 {
+		t.Fatalf("QueryMetric() = %v, want most recent sample 7", got)
+	}
+}
@@ -0,0 +1,33 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"keda-external-scaler-yc-monitoring/internal/logger"
+)
+
+// Backend reads the series matching a query over a time range. Values are
+// returned as stored, NaN, infinities and gaps included, so that every
+// backend goes through the same NaN handling and aggregation in QueryMetric.
+type Backend interface {
+	Read(ctx context.Context, req ReadRequest, logger *logger.Logger) ([]RawSeries, error)
+}
+
+type ReadRequest struct {
+	Query        string
+	FolderID     string
+	From         time.Time
+	To           time.Time
+	Downsampling DownsamplingOptions
+	ScaledObject string // Key for fair queuing between ScaledObjects
+}
+
+// RawSeries is a labeled series as read from a backend. Timestamps are Unix
+// milliseconds; Values may be shorter or longer than Timestamps.
+type RawSeries struct {
+	Name       string
+	Labels     map[string]string
+	Timestamps []int64
+	Values     []Value
+}
@@ -0,0 +1,141 @@
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"keda-external-scaler-yc-monitoring/internal/logger"
+)
+
+type BaselineMode string
+
+const (
+	BaselineRatio      BaselineMode = "ratio"
+	BaselineDifference BaselineMode = "difference"
+	BaselineFloor      BaselineMode = "floor"
+)
+
+type BaselineOptions struct {
+	Offset time.Duration // How far back the baseline window is shifted, e.g. 7 days
+	Mode   BaselineMode
+	Factor float64 // Scales the baseline before it is compared with the current value
+}
+
+func (b BaselineOptions) Enabled() bool {
+	return b.Offset > 0
+}
+
+func ParseBaselineOptions(metadata map[string]string) (BaselineOptions, error) {
+	s := strings.TrimSpace(metadata["baseline.offset"])
+	if s == "" {
+		return BaselineOptions{}, nil
+	}
+
+	offset, err := parseLongDuration(s)
+	if err != nil || offset <= 0 {
+		return BaselineOptions{}, fmt.Errorf("baseline.offset must be a positive duration such as 7d or 24h: %q", s)
+	}
+	opts := BaselineOptions{Offset: offset, Factor: 1}
+
+	switch strings.ToLower(strings.TrimSpace(metadata["baseline.mode"])) {
+	case "", "ratio":
+		opts.Mode = BaselineRatio
+	case "difference", "diff", "delta":
+		opts.Mode = BaselineDifference
+	case "floor", "min":
+		opts.Mode = BaselineFloor
+	default:
+		return BaselineOptions{}, fmt.Errorf("unsupported baseline.mode: %q", metadata["baseline.mode"])
+	}
+
+	if f := metadata["baseline.factor"]; f != "" {
+		v, err := parseFiniteFloat("baseline.factor", f)
+		if err != nil {
+			return BaselineOptions{}, err
+		}
+		if v <= 0 {
+			return BaselineOptions{}, fmt.Errorf("baseline.factor must be positive: %q", f)
+		}
+		opts.Factor = v
+	}
+
+	return opts, nil
+}
+
+// parseLongDuration extends time.ParseDuration with whole-day ("d") and
+// whole-week ("w") units.
+func parseLongDuration(s string) (time.Duration, error) {
+	units := map[byte]time.Duration{'d': 24 * time.Hour, 'w': 7 * 24 * time.Hour}
+	if unit, ok := units[s[len(s)-1]]; ok {
+		n, err := strconv.Atoi(s[:len(s)-1])
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration %q", s)
+		}
+		return time.Duration(n) * unit, nil
+	}
+	return time.ParseDuration(s)
+}
+
+// CompareBaseline combines the current value with the (already scaled)
+// baseline value according to mode.
+func CompareBaseline(current, baseline float64, mode BaselineMode) (float64, error) {
+	switch mode {
+	case BaselineRatio:
+		if baseline == 0 {
+			return 0, fmt.Errorf("baseline value is zero, ratio is undefined")
+		}
+		return current / baseline, nil
+	case BaselineDifference:
+		return current - baseline, nil
+	case BaselineFloor:
+		if baseline > current {
+			return baseline, nil
+		}
+		return current, nil
+	default:
+		return 0, fmt.Errorf("unsupported baseline mode: %q", mode)
+	}
+}
+
+// queryWithBaseline runs the query over the current window and again over the
+// same window shifted back by the baseline offset, then compares the two.
+func queryWithBaseline(ctx context.Context, backend Backend, options QueryOptions, logger *logger.Logger) (float64, error) {
+	baseline := options.Baseline
+
+	currentOptions := options
+	currentOptions.Baseline = BaselineOptions{}
+	currentOptions.Transform = ValueTransform{}
+
+	offset := 30 * time.Second
+	if options.TimeWindowOffset != "" {
+		if d, err := time.ParseDuration(options.TimeWindowOffset); err == nil {
+			offset = d
+		}
+	}
+	baselineOptions := currentOptions
+	baselineOptions.TimeWindowOffset = (offset + baseline.Offset).String()
+
+	current, err := QueryMetric(ctx, backend, currentOptions, logger)
+	if err != nil {
+		return 0, err
+	}
+
+	past, err := QueryMetric(ctx, backend, baselineOptions, logger)
+	if err != nil {
+		return 0, fmt.Errorf("baseline query (offset %v): %w", baseline.Offset, err)
+	}
+	past *= baseline.Factor
+
+	result, err := CompareBaseline(current, past, baseline.Mode)
+	if err != nil {
+		logger.Error("Baseline comparison failed: %v", err)
+		return 0, err
+	}
+	logger.Debug("Baseline %s: current=%f, baseline=%f (offset %v, factor %g) -> %f",
+		baseline.Mode, current, past, baseline.Offset, baseline.Factor, result)
+
+	return applyTransform(result, options.Transform, logger), nil
+}
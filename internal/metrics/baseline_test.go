@@ -0,0 +1,96 @@
+package metrics
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestParseBaselineOptions(t *testing.T) {
+	opts, err := ParseBaselineOptions(map[string]string{"baseline.offset": "7d"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if opts.Offset != 7*24*time.Hour || opts.Mode != BaselineRatio || opts.Factor != 1 {
+		t.Errorf("got %+v", opts)
+	}
+
+	opts, err = ParseBaselineOptions(map[string]string{"baseline.offset": "1w", "baseline.mode": "floor", "baseline.factor": "0.8"})
+	if err != nil || opts.Offset != 7*24*time.Hour || opts.Mode != BaselineFloor || opts.Factor != 0.8 {
+		t.Errorf("got %+v, %v", opts, err)
+	}
+
+	opts, err = ParseBaselineOptions(map[string]string{})
+	if err != nil || opts.Enabled() {
+		t.Errorf("empty metadata: %+v, %v", opts, err)
+	}
+
+	for _, metadata := range []map[string]string{
+		{"baseline.offset": "xd"},
+		{"baseline.offset": "-24h"},
+		{"baseline.offset": "7d", "baseline.mode": "max"},
+		{"baseline.offset": "7d", "baseline.factor": "0"},
+	} {
+		if _, err := ParseBaselineOptions(metadata); err == nil {
+			t.Errorf("expected error for %v", metadata)
+		}
+	}
+}
+
+func TestCompareBaseline(t *testing.T) {
+	tests := []struct {
+		mode              BaselineMode
+		current, baseline float64
+		want              float64
+	}{
+		{BaselineRatio, 150, 100, 1.5},
+		{BaselineDifference, 150, 100, 50},
+		{BaselineFloor, 80, 100, 100},
+		{BaselineFloor, 120, 100, 120},
+	}
+	for _, tt := range tests {
+		got, err := CompareBaseline(tt.current, tt.baseline, tt.mode)
+		if err != nil || got != tt.want {
+			t.Errorf("CompareBaseline(%v, %v, %s) = %v, %v; want %v", tt.current, tt.baseline, tt.mode, got, err, tt.want)
+		}
+	}
+	if _, err := CompareBaseline(1, 0, BaselineRatio); err == nil {
+		t.Error("expected error for zero baseline ratio")
+	}
+}
+
+func TestQueryMetricWithBaseline(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		var q MetricQuery
+		if err := json.NewDecoder(r.Body).Decode(&q); err != nil {
+			t.Fatalf("decode request: %v", err)
+		}
+		to, _ := time.Parse(time.RFC3339, q.ToTime)
+		value := "200"
+		if time.Since(to) > 24*time.Hour {
+			value = "100"
+		}
+		w.Write([]byte(`{"metrics":[{"name":"m","timeseries":{"timestamps":[1000],"doubleValues":[` + value + `]}}]}`))
+	})
+
+	multiplier := 10.0
+	got, err := client.QueryMetric(context.Background(), QueryOptions{
+		Query:             "q",
+		FolderID:          "folder",
+		NaNStrategy:       NaNStrategySkip,
+		InfStrategy:       NaNStrategySkip,
+		AggregationMethod: AggregationAvg,
+		Transform:         ValueTransform{Multiplier: &multiplier},
+		Baseline:          BaselineOptions{Offset: 7 * 24 * time.Hour, Mode: BaselineRatio, Factor: 1},
+	}, testLogger())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// The transform applies to the ratio, not to each query.
+	if math.Abs(got-20) > 1e-9 {
+		t.Errorf("got %v, want 20", got)
+	}
+}
@@ -0,0 +1,62 @@
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"keda-external-scaler-yc-monitoring/internal/breaker"
+	"keda-external-scaler-yc-monitoring/internal/logger"
+)
+
+// getToken acquires an IAM token behind the IAM circuit breaker.
+func (c *Client) getToken(ctx context.Context, logger *logger.Logger) (string, error) {
+	if err := c.iamBreaker.Allow(); err != nil {
+		return "", fmt.Errorf("IAM token requests are suspended: %w", err)
+	}
+	token, err := c.auth.GetToken(ctx)
+	c.recordCircuit(ctx, "IAM", c.iamBreaker, err, err == nil, logger)
+	return token, err
+}
+
+// isCircuitFailure reports whether a Monitoring API outcome indicates that
+// the folder or the API is unavailable, as opposed to a problem with a single
+// query. Undecodable responses are not failures.
+func isCircuitFailure(status int, err error) bool {
+	if err != nil {
+		return status == 0
+	}
+	switch status {
+	case http.StatusUnauthorized, http.StatusForbidden, http.StatusTooManyRequests:
+		return true
+	}
+	return status >= http.StatusInternalServerError
+}
+
+// recordCircuit reports the outcome of a call allowed by b. A call that failed
+// because the caller gave up says nothing about the service, so it only frees
+// the half-open probe slot.
+func (c *Client) recordCircuit(ctx context.Context, name string, b *breaker.Breaker, err error, success bool, logger *logger.Logger) {
+	if err != nil && ctx.Err() != nil {
+		b.Release()
+		return
+	}
+	from, to := b.Record(success)
+	switch {
+	case from == to:
+	case to == breaker.Open:
+		logger.Warn("Circuit breaker for %s opened after failures (was %s)", name, from)
+	default:
+		logger.Info("Circuit breaker for %s is %s (was %s)", name, to, from)
+	}
+}
+
+// CircuitStates returns the state of the IAM breaker and of every folder
+// breaker in use, keyed by "iam" and "folder/<id>".
+func (c *Client) CircuitStates() map[string]breaker.State {
+	states := map[string]breaker.State{"iam": c.iamBreaker.State()}
+	for folderID, state := range c.folderBreakers.States() {
+		states["folder/"+folderID] = state
+	}
+	return states
+}
@@ -0,0 +1,123 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"keda-external-scaler-yc-monitoring/internal/breaker"
+	"keda-external-scaler-yc-monitoring/internal/config"
+)
+
+type failingToken struct{ calls *int }
+
+func (f failingToken) GetToken(context.Context) (string, error) {
+	*f.calls++
+	return "", errors.New("IAM unavailable")
+}
+
+func TestQueryMetricFailsFastWhenFolderCircuitOpen(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	client := newClientWithConfig(staticToken("test-token"), &config.Config{
+		MonitoringEndpoint:      server.URL,
+		APITimeout:              time.Second,
+		BreakerFailureThreshold: 2,
+		BreakerOpenTimeout:      time.Minute,
+	})
+
+	for i := 0; i < 4; i++ {
+		if _, err := client.QueryMetric(context.Background(), retryTestOptions, testLogger()); err == nil {
+			t.Fatal("expected error")
+		}
+	}
+	if calls != 2 {
+		t.Errorf("API calls = %d, want 2 before the circuit opened", calls)
+	}
+	if got := client.CircuitStates()["folder/folder"]; got != breaker.Open {
+		t.Errorf("folder circuit = %s, want open", got)
+	}
+
+	// Other folders are unaffected.
+	other := retryTestOptions
+	other.FolderID = "other"
+	client.QueryMetric(context.Background(), other, testLogger())
+	if calls != 3 {
+		t.Errorf("API calls = %d, want a request for the other folder", calls)
+	}
+}
+
+func TestQueryMetricFailsFastWhenIAMCircuitOpen(t *testing.T) {
+	tokenCalls := 0
+	client := newClientWithConfig(failingToken{&tokenCalls}, &config.Config{
+		MonitoringEndpoint:      "http://unused.invalid",
+		APITimeout:              time.Second,
+		BreakerFailureThreshold: 1,
+		BreakerOpenTimeout:      time.Minute,
+	})
+
+	for i := 0; i < 3; i++ {
+		client.QueryMetric(context.Background(), retryTestOptions, testLogger())
+	}
+	if tokenCalls != 1 {
+		t.Errorf("token calls = %d, want 1", tokenCalls)
+	}
+	if got := client.CircuitStates()["iam"]; got != breaker.Open {
+		t.Errorf("IAM circuit = %s, want open", got)
+	}
+}
+
+func TestCancelledProbeKeepsCircuitHalfOpen(t *testing.T) {
+	var fail atomic.Bool
+	fail.Store(true)
+	done := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if fail.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		select {
+		case <-r.Context().Done():
+		case <-done:
+		}
+	}))
+	defer server.Close()
+	defer close(done)
+
+	client := newClientWithConfig(staticToken("test-token"), &config.Config{
+		MonitoringEndpoint:      server.URL,
+		APITimeout:              time.Second,
+		BreakerFailureThreshold: 1,
+		BreakerOpenTimeout:      10 * time.Millisecond,
+	})
+	if _, err := client.QueryMetric(context.Background(), retryTestOptions, testLogger()); err == nil {
+		t.Fatal("expected error")
+	}
+	time.Sleep(20 * time.Millisecond)
+
+	// The probe is cancelled by the caller before the API answers.
+	fail.Store(false)
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := client.QueryMetric(ctx, retryTestOptions, testLogger()); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("cancelled probe error = %v, want %v", err, context.DeadlineExceeded)
+	}
+	if got := client.CircuitStates()["folder/folder"]; got != breaker.HalfOpen {
+		t.Fatalf("folder circuit after cancelled probe = %s, want half-open", got)
+	}
+
+	// The next call is let through as a new probe.
+	fail.Store(true)
+	if _, err := client.QueryMetric(context.Background(), retryTestOptions, testLogger()); errors.Is(err, breaker.ErrOpen) {
+		t.Fatalf("call after cancelled probe was rejected: %v", err)
+	}
+}
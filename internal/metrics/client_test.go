@@ -0,0 +1,130 @@
+package metrics
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"keda-external-scaler-yc-monitoring/internal/auth"
+	"keda-external-scaler-yc-monitoring/internal/config"
+	"keda-external-scaler-yc-monitoring/internal/httpclient"
+	"keda-external-scaler-yc-monitoring/internal/logger"
+)
+
+type staticToken string
+
+func (s staticToken) GetToken(context.Context) (string, error) {
+	return string(s), nil
+}
+
+func newTestClient(t testing.TB, handler http.HandlerFunc) *Client {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	return newClientWithConfig(staticToken("test-token"), &config.Config{
+		MonitoringEndpoint: server.URL,
+		APITimeout:         time.Second,
+	})
+}
+
+func newClientWithConfig(auth auth.TokenProvider, cfg *config.Config) *Client {
+	return NewClient(auth, cfg, httpclient.New(cfg))
+}
+
+func respondWith(body string) http.HandlerFunc {
+	return func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(body))
+	}
+}
+
+func testLogger() *logger.Logger {
+	return logger.NewLogger(map[string]string{"logLevel": "none"}, "test")
+}
+
+func TestQueryMetricFillsGapsPerSeries(t *testing.T) {
+	client := newTestClient(t, respondWith(`{"metrics":[
+		{"name":"a","timeseries":{"timestamps":[1000,2000],"doubleValues":[100,"NaN"]}},
+		{"name":"b","timeseries":{"timestamps":[1000,2000],"doubleValues":["NaN",5]}}
+	]}`))
+
+	got, err := client.QueryMetric(context.Background(), QueryOptions{
+		Query:             "q",
+		FolderID:          "folder",
+		NaNStrategy:       NaNStrategyLastValid,
+		InfStrategy:       NaNStrategyLastValid,
+		AggregationMethod: AggregationSum,
+	}, testLogger())
+	if err != nil {
+		t.Fatalf("QueryMetric() error = %v", err)
+	}
+	// Series b has no earlier value, so its leading NaN must not borrow 100 from series a.
+	if got != 205 {
+		t.Fatalf("QueryMetric() = %v, want 205", got)
+	}
+}
+
+func TestQueryMetricFallsBackOnEmptyResult(t *testing.T) {
+	var requests []string
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		var q MetricQuery
+		json.NewDecoder(r.Body).Decode(&q)
+		requests = append(requests, r.URL.Query().Get("folderId")+"/"+q.Query)
+		if q.Query == "old" {
+			w.Write([]byte(`{"metrics":[{"name":"m","timeseries":{"timestamps":[1000],"doubleValues":[42]}}]}`))
+			return
+		}
+		w.Write([]byte(`{"metrics":[]}`))
+	})
+
+	options := QueryOptions{
+		Query:             "new",
+		FolderID:          "folder",
+		FallbackQuery:     "old",
+		FallbackFolderID:  "legacy",
+		NaNStrategy:       NaNStrategyZero,
+		InfStrategy:       NaNStrategyZero,
+		AggregationMethod: AggregationAvg,
+	}
+	got, err := client.QueryMetric(context.Background(), options, testLogger())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got != 42 {
+		t.Errorf("got %v, want 42", got)
+	}
+	if len(requests) != 2 || requests[0] != "folder/new" || requests[1] != "legacy/old" {
+		t.Errorf("requests = %v", requests)
+	}
+
+	options.FallbackQuery = "missing"
+	if _, err := client.QueryMetric(context.Background(), options, testLogger()); err != nil {
+		t.Errorf("fallback with zero strategy should return 0, got error %v", err)
+	}
+}
+
+func TestQueryMetricCancelsRequestWithContext(t *testing.T) {
+	canceled := make(chan struct{})
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		// The server only notices a closed connection once the body is read.
+		io.ReadAll(r.Body)
+		<-r.Context().Done()
+		close(canceled)
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := client.QueryMetric(ctx, QueryOptions{Query: "q", FolderID: "folder"}, testLogger()); err == nil {
+		t.Fatal("expected error after cancellation")
+	}
+
+	select {
+	case <-canceled:
+	case <-time.After(time.Second):
+		t.Fatal("request was not canceled with the caller's context")
+	}
+}
@@ -0,0 +1,256 @@
+package metrics
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+const (
+	defaultMaxResponseBytes = 32 << 20
+	maxErrorResponseBytes   = 64 << 10
+)
+
+// Values is a metric value array. Like Timestamps it is decoded in a single
+// pass into a slice allocated once, instead of element by element through
+// reflection.
+type Values []Value
+
+func (vs *Values) UnmarshalJSON(data []byte) error {
+	n, err := arrayLength(data)
+	if n < 0 || err != nil {
+		*vs = nil
+		return err
+	}
+
+	out := make(Values, 0, n)
+	err = scanArray(data, func(elem []byte) error {
+		v, err := decodeValue(elem)
+		out = append(out, v)
+		return err
+	})
+	*vs = out
+	return err
+}
+
+type Timestamps []int64
+
+func (ts *Timestamps) UnmarshalJSON(data []byte) error {
+	n, err := arrayLength(data)
+	if n < 0 || err != nil {
+		*ts = nil
+		return err
+	}
+
+	out := make(Timestamps, 0, n)
+	err = scanArray(data, func(elem []byte) error {
+		t, err := strconv.ParseInt(string(elem), 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid timestamp %s", elem)
+		}
+		out = append(out, t)
+		return nil
+	})
+	*ts = out
+	return err
+}
+
+// arrayLength validates the array brackets and counts its elements; it
+// returns -1 for null.
+func arrayLength(data []byte) (int, error) {
+	data = bytes.TrimSpace(data)
+	if bytes.Equal(data, []byte("null")) {
+		return -1, nil
+	}
+	if len(data) < 2 || data[0] != '[' || data[len(data)-1] != ']' {
+		return 0, fmt.Errorf("expected a JSON array")
+	}
+	return countElements(data), nil
+}
+
+// scanArray calls fn with the raw bytes of every element of a JSON array of
+// scalars.
+func scanArray(data []byte, fn func(elem []byte) error) error {
+	data = bytes.TrimSpace(data)
+	for i := 1; ; {
+		i = skipSpace(data, i)
+		if data[i] == ']' {
+			return nil
+		}
+
+		end := elementEnd(data, i)
+		if end == i || end >= len(data) {
+			return fmt.Errorf("malformed JSON array")
+		}
+		if err := fn(data[i:end]); err != nil {
+			return err
+		}
+
+		i = skipSpace(data, end)
+		if data[i] == ',' {
+			i++
+		}
+	}
+}
+
+func decodeValue(elem []byte) (Value, error) {
+	switch {
+	case string(elem) == "null":
+		return Value{Kind: ValueMissing}, nil
+	case elem[0] == '"':
+		switch string(elem) {
+		case `"NaN"`:
+			return Value{Kind: ValueNaN}, nil
+		case `"Infinity"`:
+			return Value{Kind: ValuePosInf}, nil
+		case `"-Infinity"`:
+			return Value{Kind: ValueNegInf}, nil
+		}
+		// Uncommon spellings and escaped strings take the general path.
+		var v Value
+		err := v.UnmarshalJSON(elem)
+		return v, err
+	}
+
+	f, err := strconv.ParseFloat(string(elem), 64)
+	if err != nil {
+		return Value{}, fmt.Errorf("invalid metric value %s", elem)
+	}
+	return valueFromFloat(f), nil
+}
+
+// countElements counts the elements of a JSON array of scalars so the result
+// can be allocated once.
+func countElements(data []byte) int {
+	n, inString, empty := 1, false, true
+	for i := 1; i < len(data)-1; i++ {
+		c := data[i]
+		switch {
+		case inString && c == '\\':
+			i++
+		case c == '"':
+			inString = !inString
+		case !inString && c == ',':
+			n++
+		}
+		if c != ' ' && c != '\t' && c != '\n' && c != '\r' {
+			empty = false
+		}
+	}
+	if empty {
+		return 0
+	}
+	return n
+}
+
+func skipSpace(data []byte, i int) int {
+	for i < len(data) && (data[i] == ' ' || data[i] == '\t' || data[i] == '\n' || data[i] == '\r') {
+		i++
+	}
+	return i
+}
+
+func elementEnd(data []byte, i int) int {
+	if data[i] == '"' {
+		for i++; i < len(data); i++ {
+			switch data[i] {
+			case '\\':
+				i++
+			case '"':
+				return i + 1
+			}
+		}
+		return len(data)
+	}
+	for i < len(data) && data[i] != ',' && data[i] != ']' && data[i] != ' ' && data[i] != '\t' && data[i] != '\n' && data[i] != '\r' {
+		i++
+	}
+	return i
+}
+
+// decodeResponse decodes a read response from r, reading at most the
+// configured maximum response size. The metrics array is decoded one series at
+// a time, so only the series being decoded is held in raw form. The raw body
+// is only buffered and returned when keepBody is set, for debug logging.
+func (c *Client) decodeResponse(r io.Reader, out *MetricResponse, keepBody bool) ([]byte, error) {
+	limit := c.config.MaxResponseBytes
+	if limit <= 0 {
+		limit = defaultMaxResponseBytes
+	}
+	limited := &io.LimitedReader{R: r, N: limit + 1}
+
+	var raw *bytes.Buffer
+	var src io.Reader = limited
+	if keepBody {
+		raw = &bytes.Buffer{}
+		src = io.TeeReader(limited, raw)
+	}
+
+	err := decodeMetrics(json.NewDecoder(src), out)
+	var body []byte
+	if raw != nil {
+		body = raw.Bytes()
+	}
+	if limited.N <= 0 {
+		return body, fmt.Errorf("response exceeds the maximum size of %d bytes", limit)
+	}
+	if err != nil {
+		return body, fmt.Errorf("failed to parse response: %v", err)
+	}
+	return body, nil
+}
+
+// decodeMetrics walks the top-level object token by token and decodes the
+// elements of its metrics array one by one. Other fields are skipped.
+func decodeMetrics(dec *json.Decoder, out *MetricResponse) error {
+	if err := expectDelim(dec, '{'); err != nil {
+		return err
+	}
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		if key, _ := tok.(string); key != "metrics" {
+			var skip json.RawMessage
+			if err := dec.Decode(&skip); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if tok, err = dec.Token(); err != nil {
+			return err
+		}
+		if tok == nil {
+			out.Metrics = nil
+			continue
+		}
+		if d, ok := tok.(json.Delim); !ok || d != '[' {
+			return fmt.Errorf("expected metrics to be an array, got %v", tok)
+		}
+		for dec.More() {
+			out.Metrics = append(out.Metrics, MetricData{})
+			if err := dec.Decode(&out.Metrics[len(out.Metrics)-1]); err != nil {
+				return err
+			}
+		}
+		if err := expectDelim(dec, ']'); err != nil {
+			return err
+		}
+	}
+	return expectDelim(dec, '}')
+}
+
+func expectDelim(dec *json.Decoder, want json.Delim) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if d, ok := tok.(json.Delim); !ok || d != want {
+		return fmt.Errorf("expected %v, got %v", want, tok)
+	}
+	return nil
+}
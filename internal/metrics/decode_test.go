@@ -0,0 +1,207 @@
+package metrics
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"keda-external-scaler-yc-monitoring/internal/config"
+)
+
+func TestValuesUnmarshalJSON(t *testing.T) {
+	var got Values
+	input := `[ 1.5, "NaN", null, "Infinity","-Infinity", -2e3, "nan", "4", 0 ]`
+	if err := json.Unmarshal([]byte(input), &got); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+
+	want := []Value{
+		FiniteValue(1.5), {Kind: ValueNaN}, {Kind: ValueMissing}, {Kind: ValuePosInf}, {Kind: ValueNegInf},
+		FiniteValue(-2000), {Kind: ValueNaN}, FiniteValue(4), FiniteValue(0),
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d values, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("value %d = %v, want %v", i, got[i], want[i])
+		}
+	}
+
+	// The single-pass decoder must agree with per-element decoding.
+	var reference []Value
+	if err := json.Unmarshal([]byte(input), &reference); err != nil {
+		t.Fatalf("reference Unmarshal() error = %v", err)
+	}
+	for i := range reference {
+		if got[i] != reference[i] {
+			t.Errorf("value %d = %v, per-element decoding gives %v", i, got[i], reference[i])
+		}
+	}
+
+	for _, input := range []string{`[]`, ` [ ] `} {
+		if err := json.Unmarshal([]byte(input), &got); err != nil || len(got) != 0 {
+			t.Errorf("Unmarshal(%s) = %v, %v", input, got, err)
+		}
+	}
+	for _, input := range []string{`["x"]`, `[true]`, `{}`} {
+		if err := json.Unmarshal([]byte(input), &got); err == nil {
+			t.Errorf("Unmarshal(%s) should fail", input)
+		}
+	}
+	for _, input := range []string{`[1,`, `[,]`, `["NaN]`} {
+		if err := got.UnmarshalJSON([]byte(input)); err == nil {
+			t.Errorf("UnmarshalJSON(%s) should fail", input)
+		}
+	}
+}
+
+func TestDecodeResponse(t *testing.T) {
+	client := newClientWithConfig(staticToken("test-token"), &config.Config{})
+	input := `{"metrics":[{"name":"a","labels":{"host":"h1"},"timeseries":{"timestamps":[1,2],"doubleValues":[1.5,"NaN"]}},` +
+		`{"name":"b","extra":{"nested":[1,2]},"timeseries":{"timestamps":[3],"int64Values":[7]}}],"nextPageToken":"x"}`
+
+	var resp MetricResponse
+	body, err := client.decodeResponse(strings.NewReader(input), &resp, false)
+	if err != nil || body != nil {
+		t.Fatalf("decodeResponse() = %q, %v", body, err)
+	}
+	if len(resp.Metrics) != 2 || resp.Metrics[0].Labels["host"] != "h1" || resp.Metrics[1].Name != "b" {
+		t.Fatalf("decoded metrics = %+v", resp.Metrics)
+	}
+	if got := resp.Metrics[1].Timeseries.Values(); len(got) != 1 || got[0] != FiniteValue(7) {
+		t.Errorf("int64 values = %v", got)
+	}
+
+	// The body is only kept for debug logging.
+	resp = MetricResponse{}
+	body, err = client.decodeResponse(strings.NewReader(input), &resp, true)
+	if err != nil || string(body) != input || len(resp.Metrics) != 2 {
+		t.Fatalf("decodeResponse() with body = %q, %d metrics, %v", body, len(resp.Metrics), err)
+	}
+
+	for _, input := range []string{`{"metrics":null}`, `{}`} {
+		resp = MetricResponse{}
+		if _, err := client.decodeResponse(strings.NewReader(input), &resp, false); err != nil || len(resp.Metrics) != 0 {
+			t.Errorf("decodeResponse(%s) = %d metrics, %v", input, len(resp.Metrics), err)
+		}
+	}
+	for _, input := range []string{``, `[]`, `{"metrics":{}}`, `{"metrics":[{"timeseries":{"timestamps":"x"}}]}`, `{"metrics":[]`} {
+		if _, err := client.decodeResponse(strings.NewReader(input), &resp, false); err == nil {
+			t.Errorf("decodeResponse(%s) should fail", input)
+		}
+	}
+}
+
+func TestQueryMetricRejectsOversizedResponse(t *testing.T) {
+	body := largeResponse(10, 100)
+	client := newTestClient(t, respondWith(body))
+	client.config.MaxResponseBytes = int64(len(body) - 1)
+
+	_, err := client.QueryMetric(context.Background(), retryTestOptions, testLogger())
+	if err == nil || !strings.Contains(err.Error(), "maximum size") {
+		t.Fatalf("QueryMetric() error = %v, want maximum size error", err)
+	}
+
+	client.config.MaxResponseBytes = int64(len(body))
+	if _, err := client.QueryMetric(context.Background(), retryTestOptions, testLogger()); err != nil {
+		t.Fatalf("QueryMetric() at the limit = %v", err)
+	}
+}
+
+// largeResponse builds a read response with the given number of series and
+// points per series, mixing finite values with NaN and gaps.
+func largeResponse(series, points int) string {
+	var b strings.Builder
+	b.WriteString(`{"metrics":[`)
+	for s := 0; s < series; s++ {
+		if s > 0 {
+			b.WriteByte(',')
+		}
+		fmt.Fprintf(&b, `{"name":"m%d","labels":{"host":"h%d"},"type":"DGAUGE","timeseries":{"timestamps":[`, s, s)
+		for p := 0; p < points; p++ {
+			if p > 0 {
+				b.WriteByte(',')
+			}
+			fmt.Fprintf(&b, "%d", 1700000000000+int64(p)*15000)
+		}
+		b.WriteString(`],"doubleValues":[`)
+		for p := 0; p < points; p++ {
+			if p > 0 {
+				b.WriteByte(',')
+			}
+			switch p % 50 {
+			case 7:
+				b.WriteString(`"NaN"`)
+			case 23:
+				b.WriteString("null")
+			default:
+				fmt.Fprintf(&b, "%g", float64(p)*1.25+float64(s))
+			}
+		}
+		b.WriteString(`]}}`)
+	}
+	b.WriteString(`]}`)
+	return b.String()
+}
+
+func BenchmarkDecodeResponse(b *testing.B) {
+	body := []byte(largeResponse(50, 2000))
+	client := newClientWithConfig(staticToken("test-token"), &config.Config{})
+	b.SetBytes(int64(len(body)))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var resp MetricResponse
+		if _, err := client.decodeResponse(bytes.NewReader(body), &resp, false); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkDecodeResponseBuffered decodes the same response the way it was
+// decoded before decodeResponse: the whole body is read into memory and then
+// unmarshaled element by element through reflection.
+func BenchmarkDecodeResponseBuffered(b *testing.B) {
+	body := []byte(largeResponse(50, 2000))
+	b.SetBytes(int64(len(body)))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		data, err := ioutil.ReadAll(bytes.NewReader(body))
+		if err != nil {
+			b.Fatal(err)
+		}
+		var resp struct {
+			Metrics []struct {
+				Name       string            `json:"name"`
+				Labels     map[string]string `json:"labels"`
+				Type       string            `json:"type"`
+				Timeseries struct {
+					Timestamps   []int64 `json:"timestamps"`
+					DoubleValues []Value `json:"doubleValues,omitempty"`
+					Int64Values  []int64 `json:"int64Values,omitempty"`
+				} `json:"timeseries"`
+			} `json:"metrics"`
+		}
+		if err := json.Unmarshal(data, &resp); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkQueryMetricLargeResponse(b *testing.B) {
+	client := newTestClient(b, respondWith(largeResponse(50, 2000)))
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := client.QueryMetric(context.Background(), retryTestOptions, testLogger()); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package metrics
+
+import "time"
+
+const defaultDownsamplingResolution = 10
+
+// gridSteps are the intervals auto downsampling chooses from, so that grid
+// boundaries fall on round wall-clock times.
+var gridSteps = []time.Duration{
+	time.Second, 2 * time.Second, 5 * time.Second, 10 * time.Second, 15 * time.Second, 30 * time.Second,
+	time.Minute, 2 * time.Minute, 5 * time.Minute, 10 * time.Minute, 15 * time.Minute, 30 * time.Minute,
+	time.Hour, 2 * time.Hour, 3 * time.Hour, 6 * time.Hour, 12 * time.Hour, 24 * time.Hour,
+}
+
+// AutoGridInterval returns the smallest grid step that yields at most
+// resolution points over window.
+func AutoGridInterval(window time.Duration, resolution int) time.Duration {
+	if resolution <= 0 {
+		resolution = defaultDownsamplingResolution
+	}
+	want := window / time.Duration(resolution)
+	for _, step := range gridSteps {
+		if step >= want {
+			return step
+		}
+	}
+	return gridSteps[len(gridSteps)-1]
+}
+
+// AlignToGrid moves end back to a grid boundary and extends the window to a
+// whole number of grid intervals, so that every bucket in the range is
+// complete.
+func AlignToGrid(start, end time.Time, interval time.Duration) (time.Time, time.Time) {
+	step := interval.Milliseconds()
+	alignedEnd := time.UnixMilli(floorDiv(end.UnixMilli(), step) * step).UTC()
+
+	buckets := (end.Sub(start) + interval - 1) / interval
+	if buckets < 1 {
+		buckets = 1
+	}
+	return alignedEnd.Add(-buckets * interval), alignedEnd
+}
@@ -0,0 +1,73 @@
+package metrics
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestAutoGridInterval(t *testing.T) {
+	tests := []struct {
+		window     time.Duration
+		resolution int
+		want       time.Duration
+	}{
+		{5 * time.Minute, 10, 30 * time.Second},
+		{5 * time.Minute, 7, time.Minute},
+		{time.Hour, 30, 2 * time.Minute},
+		{10 * time.Second, 100, time.Second},
+		{30 * 24 * time.Hour, 10, 24 * time.Hour},
+	}
+	for _, tt := range tests {
+		if got := AutoGridInterval(tt.window, tt.resolution); got != tt.want {
+			t.Errorf("AutoGridInterval(%v, %d) = %v, want %v", tt.window, tt.resolution, got, tt.want)
+		}
+	}
+}
+
+func TestAlignToGrid(t *testing.T) {
+	end := time.Date(2026, time.March, 1, 12, 4, 47, 0, time.UTC)
+	start, alignedEnd := AlignToGrid(end.Add(-5*time.Minute), end, 30*time.Second)
+
+	if want := time.Date(2026, time.March, 1, 12, 4, 30, 0, time.UTC); !alignedEnd.Equal(want) {
+		t.Errorf("end = %v, want %v", alignedEnd, want)
+	}
+	if want := time.Date(2026, time.March, 1, 11, 59, 30, 0, time.UTC); !start.Equal(want) {
+		t.Errorf("start = %v, want %v", start, want)
+	}
+}
+
+func TestQueryMetricAutoDownsampling(t *testing.T) {
+	var query MetricQuery
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&query)
+		w.Write([]byte(`{"metrics":[{"name":"m","timeseries":{"timestamps":[1000],"doubleValues":[1]}}]}`))
+	})
+
+	_, err := client.QueryMetric(context.Background(), QueryOptions{
+		Query:             "q",
+		FolderID:          "folder",
+		NaNStrategy:       NaNStrategySkip,
+		InfStrategy:       NaNStrategySkip,
+		AggregationMethod: AggregationAvg,
+		TimeWindow:        "10m",
+		Downsampling:      DownsamplingOptions{GridAggregation: "AVG", Mode: DownsamplingAuto, Resolution: 10, HasSettings: true},
+	}, testLogger())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if got := query.Downsampling["gridInterval"]; got != "60000" {
+		t.Errorf("gridInterval = %v, want 60000", got)
+	}
+	if _, ok := query.Downsampling["maxPoints"]; ok {
+		t.Errorf("maxPoints must not be sent in auto mode")
+	}
+	from, _ := time.Parse(time.RFC3339, query.FromTime)
+	to, _ := time.Parse(time.RFC3339, query.ToTime)
+	if from.Second() != 0 || to.Second() != 0 || to.Sub(from) != 10*time.Minute {
+		t.Errorf("range %s - %s is not aligned to the 1m grid", query.FromTime, query.ToTime)
+	}
+}
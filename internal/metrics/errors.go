@@ -0,0 +1,130 @@
+package metrics
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+)
+
+// maxErrorBodyLength bounds how much of a response body ends up in error
+// messages and logs.
+const maxErrorBodyLength = 512
+
+type APIErrorKind string
+
+const (
+	APIErrorInvalidQuery     APIErrorKind = "invalid query"
+	APIErrorUnauthenticated  APIErrorKind = "unauthenticated"
+	APIErrorPermissionDenied APIErrorKind = "permission denied"
+	APIErrorNotFound         APIErrorKind = "not found"
+	APIErrorQuotaExceeded    APIErrorKind = "quota exceeded"
+	APIErrorUnavailable      APIErrorKind = "unavailable"
+	APIErrorUnknown          APIErrorKind = "unknown"
+)
+
+// APIError is a non-200 response from the Monitoring API.
+type APIError struct {
+	StatusCode int
+	Kind       APIErrorKind
+	Status     string // Error status from the response, e.g. INVALID_ARGUMENT
+	Message    string
+	FolderID   string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("monitoring API error %d (%s): %s", e.StatusCode, e.Kind, e.Message)
+}
+
+// apiErrorBody covers both the flat {"code", "message"} error format and the
+// same fields nested under "error".
+type apiErrorBody struct {
+	Code    json.RawMessage `json:"code"`
+	Status  string          `json:"status"`
+	Message string          `json:"message"`
+	Error   *apiErrorBody   `json:"error"`
+}
+
+var grpcCodeNames = map[int]string{
+	3: "INVALID_ARGUMENT", 5: "NOT_FOUND", 7: "PERMISSION_DENIED", 8: "RESOURCE_EXHAUSTED",
+	14: "UNAVAILABLE", 16: "UNAUTHENTICATED",
+}
+
+func parseAPIError(statusCode int, body []byte, folderID string) *APIError {
+	apiErr := &APIError{StatusCode: statusCode, FolderID: folderID}
+
+	var parsed apiErrorBody
+	if err := json.Unmarshal(body, &parsed); err == nil {
+		if parsed.Error != nil {
+			parsed = *parsed.Error
+		}
+		apiErr.Message = parsed.Message
+		apiErr.Status = strings.ToUpper(parsed.Status)
+		if apiErr.Status == "" {
+			var code int
+			var name string
+			if json.Unmarshal(parsed.Code, &code) == nil {
+				apiErr.Status = grpcCodeNames[code]
+			} else if json.Unmarshal(parsed.Code, &name) == nil {
+				apiErr.Status = strings.ToUpper(name)
+			}
+		}
+	}
+	if apiErr.Message == "" {
+		apiErr.Message = truncate(strings.TrimSpace(string(body)), maxErrorBodyLength)
+	} else {
+		apiErr.Message = truncate(apiErr.Message, maxErrorBodyLength)
+	}
+	if apiErr.Message == "" {
+		apiErr.Message = http.StatusText(statusCode)
+	}
+
+	apiErr.Kind = classifyAPIError(statusCode, apiErr.Status)
+	return apiErr
+}
+
+func classifyAPIError(statusCode int, status string) APIErrorKind {
+	switch status {
+	case "INVALID_ARGUMENT", "FAILED_PRECONDITION", "OUT_OF_RANGE":
+		return APIErrorInvalidQuery
+	case "UNAUTHENTICATED":
+		return APIErrorUnauthenticated
+	case "PERMISSION_DENIED":
+		return APIErrorPermissionDenied
+	case "NOT_FOUND":
+		return APIErrorNotFound
+	case "RESOURCE_EXHAUSTED":
+		return APIErrorQuotaExceeded
+	case "UNAVAILABLE", "DEADLINE_EXCEEDED":
+		return APIErrorUnavailable
+	}
+
+	switch {
+	case statusCode == http.StatusBadRequest:
+		return APIErrorInvalidQuery
+	case statusCode == http.StatusUnauthorized:
+		return APIErrorUnauthenticated
+	case statusCode == http.StatusForbidden:
+		return APIErrorPermissionDenied
+	case statusCode == http.StatusNotFound:
+		return APIErrorNotFound
+	case statusCode == http.StatusTooManyRequests:
+		return APIErrorQuotaExceeded
+	case statusCode >= http.StatusInternalServerError:
+		return APIErrorUnavailable
+	}
+	return APIErrorUnknown
+}
+
+// truncate shortens s to at most n bytes without splitting a UTF-8 sequence.
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	cut := n
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+	return s[:cut] + fmt.Sprintf("... (%d bytes truncated)", len(s)-cut)
+}
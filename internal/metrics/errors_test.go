@@ -0,0 +1,58 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestParseAPIError(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		body    string
+		kind    APIErrorKind
+		message string
+	}{
+		{"grpc code", http.StatusBadRequest, `{"code":3,"message":"Invalid selector"}`, APIErrorInvalidQuery, "Invalid selector"},
+		{"nested status", http.StatusForbidden, `{"error":{"status":"PERMISSION_DENIED","message":"denied"}}`, APIErrorPermissionDenied, "denied"},
+		{"status wins over http code", http.StatusBadRequest, `{"code":8,"message":"quota"}`, APIErrorQuotaExceeded, "quota"},
+		{"plain text", http.StatusServiceUnavailable, "upstream connect error", APIErrorUnavailable, "upstream connect error"},
+		{"empty body", http.StatusTooManyRequests, "", APIErrorQuotaExceeded, "Too Many Requests"},
+		{"unmapped", http.StatusConflict, `{}`, APIErrorUnknown, "{}"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := parseAPIError(tt.status, []byte(tt.body), "folder")
+			if err.Kind != tt.kind || err.Message != tt.message || err.FolderID != "folder" {
+				t.Fatalf("parseAPIError() = %+v, want kind %q message %q", err, tt.kind, tt.message)
+			}
+		})
+	}
+}
+
+func TestParseAPIErrorTruncatesBody(t *testing.T) {
+	body := strings.Repeat("x", 10000)
+	err := parseAPIError(http.StatusBadGateway, []byte(body), "folder")
+	if len(err.Error()) > maxErrorBodyLength+100 || !strings.Contains(err.Message, "bytes truncated") {
+		t.Fatalf("message not truncated: %d bytes", len(err.Error()))
+	}
+	if got := truncate("héllo", 2); got != "h... (5 bytes truncated)" {
+		t.Errorf("truncate split a rune: %q", got)
+	}
+}
+
+func TestQueryMetricReturnsAPIError(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"code":3,"message":"Unknown function foo"}`))
+	})
+
+	_, err := client.QueryMetric(context.Background(), QueryOptions{Query: "foo()", FolderID: "folder"}, testLogger())
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.Kind != APIErrorInvalidQuery {
+		t.Fatalf("QueryMetric() error = %v, want invalid query APIError", err)
+	}
+}
@@ -0,0 +1,156 @@
+package metrics
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+type ForecastMethod string
+
+const (
+	ForecastNone   ForecastMethod = ""
+	ForecastLinear ForecastMethod = "linear"
+	ForecastHolt   ForecastMethod = "holt"
+)
+
+const (
+	defaultForecastHorizon = 60 * time.Second
+	defaultHoltAlpha       = 0.5
+	defaultHoltBeta        = 0.3
+)
+
+type ForecastOptions struct {
+	Method  ForecastMethod // linear trend or Holt double exponential smoothing
+	Horizon time.Duration  // How far past the latest sample to predict
+	Alpha   float64        // Holt level smoothing factor, (0, 1]
+	Beta    float64        // Holt trend smoothing factor, (0, 1]
+}
+
+func (f ForecastOptions) Enabled() bool {
+	return f.Method != ForecastNone
+}
+
+func ParseForecastOptions(metadata map[string]string) (ForecastOptions, error) {
+	opts := ForecastOptions{
+		Horizon: defaultForecastHorizon,
+		Alpha:   defaultHoltAlpha,
+		Beta:    defaultHoltBeta,
+	}
+
+	switch strings.ToLower(strings.TrimSpace(metadata["forecast.method"])) {
+	case "", "none":
+		return ForecastOptions{}, nil
+	case "linear":
+		opts.Method = ForecastLinear
+	case "holt", "holtwinters", "holt-winters":
+		opts.Method = ForecastHolt
+	default:
+		return ForecastOptions{}, fmt.Errorf("unsupported forecast.method: %q", metadata["forecast.method"])
+	}
+
+	if s := metadata["forecast.horizon"]; s != "" {
+		horizon, err := time.ParseDuration(s)
+		if err != nil || horizon < 0 {
+			return ForecastOptions{}, fmt.Errorf("forecast.horizon must be a non-negative duration: %q", s)
+		}
+		opts.Horizon = horizon
+	}
+
+	for _, p := range []struct {
+		key   string
+		value *float64
+	}{
+		{"forecast.alpha", &opts.Alpha},
+		{"forecast.beta", &opts.Beta},
+	} {
+		s := metadata[p.key]
+		if s == "" {
+			continue
+		}
+		v, err := parseFiniteFloat(p.key, s)
+		if err != nil {
+			return ForecastOptions{}, err
+		}
+		if v <= 0 || v > 1 {
+			return ForecastOptions{}, fmt.Errorf("%s must be in (0, 1]: %q", p.key, s)
+		}
+		*p.value = v
+	}
+
+	return opts, nil
+}
+
+// Forecast predicts the series value Horizon after its latest sample.
+// Series with fewer than two distinct timestamps return the latest value.
+func Forecast(points []Point, opts ForecastOptions) (float64, error) {
+	if len(points) == 0 {
+		return 0, fmt.Errorf("no values to forecast")
+	}
+
+	sorted := sortedPoints(points)
+	last := sorted[len(sorted)-1]
+	if last.Timestamp == sorted[0].Timestamp {
+		return last.Value, nil
+	}
+
+	horizon := float64(opts.Horizon.Milliseconds())
+
+	switch opts.Method {
+	case ForecastLinear:
+		slope, intercept := linearRegression(sorted, last.Timestamp)
+		return intercept + slope*horizon, nil
+	case ForecastHolt:
+		level, trend := holtSmoothing(sorted, opts.Alpha, opts.Beta)
+		return level + trend*horizon, nil
+	default:
+		return 0, fmt.Errorf("unknown forecast method: %s", opts.Method)
+	}
+}
+
+// linearRegression fits value = intercept + slope*(t - origin) by least squares,
+// with t in milliseconds.
+func linearRegression(points []Point, origin int64) (slope, intercept float64) {
+	n := float64(len(points))
+	var sumX, sumY, sumXY, sumXX float64
+	for _, p := range points {
+		x := float64(p.Timestamp - origin)
+		sumX += x
+		sumY += p.Value
+		sumXY += x * p.Value
+		sumXX += x * x
+	}
+
+	denominator := n*sumXX - sumX*sumX
+	if denominator == 0 {
+		return 0, sumY / n
+	}
+
+	slope = (n*sumXY - sumX*sumY) / denominator
+	intercept = (sumY - slope*sumX) / n
+	return slope, intercept
+}
+
+// holtSmoothing runs Holt's linear (non-seasonal Holt-Winters) smoothing over
+// irregularly spaced points, keeping the trend in units per millisecond.
+func holtSmoothing(points []Point, alpha, beta float64) (level, trend float64) {
+	level = points[0].Value
+	for i := 1; i < len(points); i++ {
+		if dt := points[i].Timestamp - points[0].Timestamp; dt > 0 {
+			trend = (points[i].Value - points[0].Value) / float64(dt)
+			break
+		}
+	}
+
+	for i := 1; i < len(points); i++ {
+		dt := float64(points[i].Timestamp - points[i-1].Timestamp)
+		if dt <= 0 {
+			continue
+		}
+		previousLevel := level
+		level = alpha*points[i].Value + (1-alpha)*(level+trend*dt)
+		trend = beta*(level-previousLevel)/dt + (1-beta)*trend
+	}
+
+	return level, trend
+}
@@ -0,0 +1,74 @@
+package metrics
+
+import (
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+func rampPoints(start, step float64, count int) []Point {
+	points := make([]Point, count)
+	for i := range points {
+		points[i] = Point{Timestamp: int64(i) * 60000, Value: start + step*float64(i)}
+	}
+	return points
+}
+
+func TestForecastLinear(t *testing.T) {
+	// 10 per minute, last sample at 40 → 90s ahead is 55.
+	points := rampPoints(0, 10, 5)
+	points[0], points[4] = points[4], points[0]
+
+	got, err := Forecast(points, ForecastOptions{Method: ForecastLinear, Horizon: 90 * time.Second})
+	if err != nil {
+		t.Fatalf("Forecast() error = %v", err)
+	}
+	if math.Abs(got-55) > 1e-9 {
+		t.Fatalf("Forecast() = %v, want 55", got)
+	}
+}
+
+func TestForecastHoltFollowsTrend(t *testing.T) {
+	points := rampPoints(100, 20, 10)
+
+	got, err := Forecast(points, ForecastOptions{Method: ForecastHolt, Horizon: time.Minute, Alpha: 0.5, Beta: 0.3})
+	if err != nil {
+		t.Fatalf("Forecast() error = %v", err)
+	}
+	if math.Abs(got-300) > 1e-6 {
+		t.Fatalf("Forecast() = %v, want 300", got)
+	}
+}
+
+func TestForecastSinglePoint(t *testing.T) {
+	got, err := Forecast([]Point{{Timestamp: 1000, Value: 7}}, ForecastOptions{Method: ForecastLinear, Horizon: time.Hour})
+	if err != nil || got != 7 {
+		t.Fatalf("Forecast() = %v, %v, want 7", got, err)
+	}
+}
+
+func TestParseForecastOptions(t *testing.T) {
+	opts, err := ParseForecastOptions(map[string]string{})
+	if err != nil || opts.Enabled() {
+		t.Fatalf("ParseForecastOptions(empty) = %+v, %v, want disabled", opts, err)
+	}
+
+	opts, err = ParseForecastOptions(map[string]string{"forecast.method": "holt", "forecast.horizon": "90s", "forecast.alpha": "0.8"})
+	if err != nil {
+		t.Fatalf("ParseForecastOptions() error = %v", err)
+	}
+	if opts.Method != ForecastHolt || opts.Horizon != 90*time.Second || opts.Alpha != 0.8 || opts.Beta != defaultHoltBeta {
+		t.Fatalf("ParseForecastOptions() = %+v", opts)
+	}
+
+	for key, metadata := range map[string]map[string]string{
+		"forecast.method":  {"forecast.method": "arima"},
+		"forecast.horizon": {"forecast.method": "linear", "forecast.horizon": "soon"},
+		"forecast.beta":    {"forecast.method": "holt", "forecast.beta": "1.5"},
+	} {
+		if _, err := ParseForecastOptions(metadata); err == nil || !strings.Contains(err.Error(), key) {
+			t.Fatalf("ParseForecastOptions(%v) error = %v, want substring %q", metadata, err, key)
+		}
+	}
+}
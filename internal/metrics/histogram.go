@@ -0,0 +1,159 @@
+package metrics
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const defaultHistogramBucketLabel = "bin"
+
+type HistogramOptions struct {
+	Quantile    float64 // Requested quantile in [0, 1]
+	BucketLabel string  // Label holding the bucket upper bound
+	Cumulative  bool    // Whether bucket counts include all lower buckets (Prometheus "le")
+	Enabled     bool
+}
+
+func ParseHistogramOptions(metadata map[string]string) (HistogramOptions, error) {
+	s := strings.TrimSpace(metadata["histogram.quantile"])
+	if s == "" {
+		return HistogramOptions{}, nil
+	}
+
+	q, err := parseFiniteFloat("histogram.quantile", s)
+	if err != nil {
+		return HistogramOptions{}, err
+	}
+	if q > 1 && q <= 100 {
+		q /= 100
+	}
+	if q < 0 || q > 1 {
+		return HistogramOptions{}, fmt.Errorf("histogram.quantile must be in [0, 1]: %q", s)
+	}
+
+	opts := HistogramOptions{
+		Quantile:    q,
+		BucketLabel: defaultHistogramBucketLabel,
+		Enabled:     true,
+	}
+	if label := strings.TrimSpace(metadata["histogram.bucketLabel"]); label != "" {
+		opts.BucketLabel = label
+	}
+
+	opts.Cumulative = opts.BucketLabel == "le"
+	switch strings.ToLower(strings.TrimSpace(metadata["histogram.cumulative"])) {
+	case "":
+	case "true", "yes", "1", "on":
+		opts.Cumulative = true
+	case "false", "no", "0", "off":
+		opts.Cumulative = false
+	default:
+		return HistogramOptions{}, fmt.Errorf("histogram.cumulative must be a boolean: %q", metadata["histogram.cumulative"])
+	}
+
+	return opts, nil
+}
+
+type HistogramBucket struct {
+	UpperBound float64
+	Count      float64
+}
+
+// BuildHistogram reduces each bucket series over time with method, then sums
+// series sharing the same bucket bound. Series without the bucket label are
+// returned separately so the caller can report them.
+func BuildHistogram(series []Series, opts HistogramOptions, method AggregationMethod) ([]HistogramBucket, []string, error) {
+	counts := map[float64]float64{}
+	var skipped []string
+
+	for _, s := range series {
+		bound, err := parseBucketBound(s.Labels[opts.BucketLabel])
+		if err != nil {
+			skipped = append(skipped, s.Name)
+			continue
+		}
+		count, err := AggregatePoints(s.Points, method)
+		if err != nil {
+			return nil, nil, err
+		}
+		counts[bound] += count
+	}
+
+	buckets := make([]HistogramBucket, 0, len(counts))
+	for bound, count := range counts {
+		buckets = append(buckets, HistogramBucket{UpperBound: bound, Count: count})
+	}
+	sort.Slice(buckets, func(i, j int) bool { return buckets[i].UpperBound < buckets[j].UpperBound })
+
+	return buckets, skipped, nil
+}
+
+// HistogramQuantile estimates the quantile by linear interpolation within the
+// bucket containing it, in the same way as Prometheus's histogram_quantile.
+// The lowest bucket is assumed to start at 0 when its bound is positive, and a
+// quantile falling into the +Inf bucket returns the highest finite bound.
+func HistogramQuantile(q float64, buckets []HistogramBucket, cumulative bool) (float64, error) {
+	if len(buckets) == 0 {
+		return 0, fmt.Errorf("no histogram buckets")
+	}
+
+	cum := make([]float64, len(buckets))
+	running := 0.0
+	for i, b := range buckets {
+		if cumulative {
+			// Guard against non-monotonic cumulative counts from partial data.
+			running = math.Max(running, b.Count)
+		} else {
+			running += b.Count
+		}
+		cum[i] = running
+	}
+
+	total := cum[len(cum)-1]
+	if total <= 0 {
+		return 0, fmt.Errorf("histogram has no observations")
+	}
+
+	rank := q * total
+	i := sort.Search(len(cum), func(i int) bool { return cum[i] >= rank })
+	if i == len(cum) {
+		i = len(cum) - 1
+	}
+
+	if math.IsInf(buckets[i].UpperBound, 1) {
+		if i == 0 {
+			return 0, fmt.Errorf("histogram has only a +Inf bucket")
+		}
+		return buckets[i-1].UpperBound, nil
+	}
+
+	lower, below := 0.0, 0.0
+	if i > 0 {
+		lower, below = buckets[i-1].UpperBound, cum[i-1]
+	} else if buckets[0].UpperBound <= 0 {
+		return buckets[0].UpperBound, nil
+	}
+
+	inBucket := cum[i] - below
+	if inBucket <= 0 {
+		return buckets[i].UpperBound, nil
+	}
+	return lower + (buckets[i].UpperBound-lower)*(rank-below)/inBucket, nil
+}
+
+func parseBucketBound(s string) (float64, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "":
+		return 0, fmt.Errorf("missing bucket bound")
+	case "inf", "+inf", "infinity", "+infinity":
+		return math.Inf(1), nil
+	}
+	v, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	if err != nil || math.IsNaN(v) {
+		return 0, fmt.Errorf("invalid bucket bound %q", s)
+	}
+	return v, nil
+}
@@ -0,0 +1,88 @@
+package metrics
+
+import (
+	"context"
+	"math"
+	"testing"
+)
+
+func TestHistogramQuantile(t *testing.T) {
+	// 100 observations: 50 ≤ 100ms, 40 in (100, 200], 9 in (200, 500], 1 above.
+	buckets := []HistogramBucket{
+		{UpperBound: 100, Count: 50},
+		{UpperBound: 200, Count: 40},
+		{UpperBound: 500, Count: 9},
+		{UpperBound: math.Inf(1), Count: 1},
+	}
+
+	tests := []struct {
+		q    float64
+		want float64
+	}{
+		{0.25, 50},
+		{0.5, 100},
+		{0.9, 200},
+		{0.95, 200 + 300*5.0/9},
+		{0.999, 500},
+	}
+
+	for _, tt := range tests {
+		got, err := HistogramQuantile(tt.q, buckets, false)
+		if err != nil {
+			t.Fatalf("HistogramQuantile(%v) error = %v", tt.q, err)
+		}
+		if math.Abs(got-tt.want) > 1e-9 {
+			t.Fatalf("HistogramQuantile(%v) = %v, want %v", tt.q, got, tt.want)
+		}
+	}
+
+	cumulative := []HistogramBucket{{100, 50}, {200, 90}, {500, 99}, {math.Inf(1), 100}}
+	if got, _ := HistogramQuantile(0.95, cumulative, true); math.Abs(got-(200+300*5.0/9)) > 1e-9 {
+		t.Fatalf("cumulative HistogramQuantile(0.95) = %v", got)
+	}
+
+	if _, err := HistogramQuantile(0.5, []HistogramBucket{{100, 0}}, false); err == nil {
+		t.Fatalf("HistogramQuantile() of empty histogram succeeded, want error")
+	}
+}
+
+func TestQueryMetricHistogramSumsMatchingBuckets(t *testing.T) {
+	// Two hosts export the same buckets; counts must be summed per bucket.
+	client := newTestClient(t, respondWith(`{"metrics":[
+		{"name":"h1","labels":{"bin":"100"},"timeseries":{"timestamps":[1000,2000],"doubleValues":[10,15]}},
+		{"name":"h1","labels":{"bin":"200"},"timeseries":{"timestamps":[1000,2000],"doubleValues":[5,5]}},
+		{"name":"h2","labels":{"bin":"100"},"timeseries":{"timestamps":[1000,2000],"doubleValues":[15,10]}},
+		{"name":"h2","labels":{"bin":"200"},"timeseries":{"timestamps":[1000,2000],"doubleValues":[10,0]}},
+		{"name":"h2","labels":{"bin":"inf"},"timeseries":{"timestamps":[1000,2000],"doubleValues":[0,0]}},
+		{"name":"total","timeseries":{"timestamps":[1000],"doubleValues":[999]}}
+	]}`))
+
+	opts, err := ParseHistogramOptions(map[string]string{"histogram.quantile": "0.9"})
+	if err != nil {
+		t.Fatalf("ParseHistogramOptions() error = %v", err)
+	}
+	got, err := client.QueryMetric(context.Background(), QueryOptions{
+		Query:             "q",
+		FolderID:          "folder",
+		NaNStrategy:       NaNStrategySkip,
+		AggregationMethod: AggregationMax,
+		Histogram:         opts,
+	}, testLogger())
+	if err != nil {
+		t.Fatalf("QueryMetric() error = %v", err)
+	}
+	// Buckets: ≤100 → 50, (100,200] → 20; rank 63 lies 13/20 into the second bucket.
+	if math.Abs(got-165) > 1e-9 {
+		t.Fatalf("QueryMetric() = %v, want 165", got)
+	}
+}
+
+func TestParseHistogramOptions(t *testing.T) {
+	opts, err := ParseHistogramOptions(map[string]string{"histogram.quantile": "99", "histogram.bucketLabel": "le"})
+	if err != nil || opts.Quantile != 0.99 || !opts.Cumulative {
+		t.Fatalf("ParseHistogramOptions() = %+v, %v", opts, err)
+	}
+	if _, err := ParseHistogramOptions(map[string]string{"histogram.quantile": "-1"}); err == nil {
+		t.Fatalf("ParseHistogramOptions(-1) succeeded, want error")
+	}
+}
@@ -0,0 +1,34 @@
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"keda-external-scaler-yc-monitoring/internal/logger"
+)
+
+// acquire waits for both the folder and the global limiter. Waiting callers
+// are queued per ScaledObject so that one busy ScaledObject cannot starve the
+// others.
+func (c *Client) acquire(ctx context.Context, req ReadRequest, logger *logger.Logger) (func(), error) {
+	start := time.Now()
+
+	releaseFolder, err := c.folderLimiters.Get(req.FolderID).Acquire(ctx, req.ScaledObject)
+	if err != nil {
+		return nil, fmt.Errorf("waiting for folder %s rate limit: %w", req.FolderID, err)
+	}
+	releaseGlobal, err := c.globalLimiter.Acquire(ctx, req.ScaledObject)
+	if err != nil {
+		releaseFolder()
+		return nil, fmt.Errorf("waiting for global rate limit: %w", err)
+	}
+
+	if waited := time.Since(start); waited > 10*time.Millisecond {
+		logger.Debug("Waited %v for Monitoring API rate limits (folder %s)", waited, req.FolderID)
+	}
+	return func() {
+		releaseGlobal()
+		releaseFolder()
+	}, nil
+}
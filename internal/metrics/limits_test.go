@@ -0,0 +1,77 @@
+package metrics
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"keda-external-scaler-yc-monitoring/internal/config"
+)
+
+func TestQueryMetricRespectsFolderMaxInFlight(t *testing.T) {
+	var inFlight, peak int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&inFlight, 1)
+		for {
+			p := atomic.LoadInt32(&peak)
+			if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+				break
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+		atomic.AddInt32(&inFlight, -1)
+		w.Write([]byte(`{"metrics":[{"name":"m","timeseries":{"timestamps":[1000],"doubleValues":[1]}}]}`))
+	}))
+	defer server.Close()
+
+	client := newClientWithConfig(staticToken("test-token"), &config.Config{
+		MonitoringEndpoint: server.URL,
+		APITimeout:         time.Second,
+		FolderMaxInFlight:  2,
+	})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			options := retryTestOptions
+			options.ScaledObject = []string{"default/a", "default/b"}[i%2]
+			if _, err := client.QueryMetric(context.Background(), options, testLogger()); err != nil {
+				t.Errorf("QueryMetric() = %v", err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if peak > 2 {
+		t.Errorf("peak concurrent requests = %d, want at most 2", peak)
+	}
+}
+
+func TestQueryMetricRateLimitHonorsContext(t *testing.T) {
+	calls := 0
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Write([]byte(`{"metrics":[{"name":"m","timeseries":{"timestamps":[1000],"doubleValues":[1]}}]}`))
+	})
+	client.config.RateLimitQPS = 0.1
+	client = newClientWithConfig(client.auth, client.config)
+
+	if _, err := client.QueryMetric(context.Background(), retryTestOptions, testLogger()); err != nil {
+		t.Fatalf("first QueryMetric() = %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := client.QueryMetric(ctx, retryTestOptions, testLogger()); err == nil {
+		t.Fatal("expected the second query to wait for a token and time out")
+	}
+	if calls != 1 {
+		t.Errorf("API calls = %d, want 1", calls)
+	}
+}
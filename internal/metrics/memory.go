@@ -0,0 +1,73 @@
+package metrics
+
+import (
+	"context"
+	"sync"
+
+	"keda-external-scaler-yc-monitoring/internal/logger"
+)
+
+// MemoryResponse is one scripted answer of a MemoryBackend.
+type MemoryResponse struct {
+	Series []RawSeries
+	Err    error
+}
+
+// MemoryBackend serves scripted responses keyed by query, for tests and
+// offline runs. Each read of a query consumes the next response of its
+// script, and the last response is repeated once the script is exhausted.
+// Queries without a script return no series.
+type MemoryBackend struct {
+	mu        sync.Mutex
+	responses map[string][]MemoryResponse
+	requests  []ReadRequest
+}
+
+func NewMemoryBackend() *MemoryBackend {
+	return &MemoryBackend{responses: map[string][]MemoryResponse{}}
+}
+
+// Set makes every read of query return series.
+func (m *MemoryBackend) Set(query string, series ...RawSeries) {
+	m.Script(query, MemoryResponse{Series: series})
+}
+
+// SetError makes every read of query fail with err.
+func (m *MemoryBackend) SetError(query string, err error) {
+	m.Script(query, MemoryResponse{Err: err})
+}
+
+// Script replaces the responses for query.
+func (m *MemoryBackend) Script(query string, responses ...MemoryResponse) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.responses[query] = responses
+}
+
+// Requests returns the reads made so far, in order.
+func (m *MemoryBackend) Requests() []ReadRequest {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]ReadRequest(nil), m.requests...)
+}
+
+func (m *MemoryBackend) Read(ctx context.Context, req ReadRequest, logger *logger.Logger) ([]RawSeries, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.requests = append(m.requests, req)
+
+	script := m.responses[req.Query]
+	if len(script) == 0 {
+		return nil, nil
+	}
+	resp := script[0]
+	if len(script) > 1 {
+		m.responses[req.Query] = script[1:]
+	}
+	logger.Debug("Memory backend: query=%s returned %d series (err: %v)", req.Query, len(resp.Series), resp.Err)
+	return resp.Series, resp.Err
+}
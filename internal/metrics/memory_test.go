@@ -0,0 +1,75 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestQueryMetricWithMemoryBackend(t *testing.T) {
+	backend := NewMemoryBackend()
+	backend.Set("requests",
+		RawSeries{
+			Name:       "a",
+			Timestamps: []int64{1000, 2000, 3000},
+			Values:     []Value{FiniteValue(10), valueFromFloat(math.NaN()), FiniteValue(30)},
+		},
+		RawSeries{
+			Name:       "b",
+			Timestamps: []int64{1000, 2000},
+			Values:     []Value{FiniteValue(20), {Kind: ValueMissing}},
+		},
+	)
+
+	options := QueryOptions{
+		Query:             "requests",
+		FolderID:          "folder",
+		TimeWindow:        "10m",
+		NaNStrategy:       NaNStrategySkip,
+		InfStrategy:       NaNStrategySkip,
+		AggregationMethod: AggregationAvg,
+	}
+	got, err := QueryMetric(context.Background(), backend, options, testLogger())
+	if err != nil {
+		t.Fatalf("QueryMetric() error = %v", err)
+	}
+	if got != 20 {
+		t.Fatalf("QueryMetric() = %v, want 20", got)
+	}
+
+	requests := backend.Requests()
+	if len(requests) != 1 {
+		t.Fatalf("backend got %d reads, want 1", len(requests))
+	}
+	if req := requests[0]; req.Query != "requests" || req.FolderID != "folder" || req.To.Sub(req.From) != 10*time.Minute {
+		t.Fatalf("read request = %+v, want requests in folder over 10m", req)
+	}
+}
+
+func TestMemoryBackendScript(t *testing.T) {
+	backend := NewMemoryBackend()
+	unavailable := errors.New("unavailable")
+	backend.Script("q",
+		MemoryResponse{Err: unavailable},
+		MemoryResponse{Series: []RawSeries{{Timestamps: []int64{1000}, Values: []Value{FiniteValue(5)}}}},
+	)
+
+	options := QueryOptions{Query: "q", AggregationMethod: AggregationLast}
+	if _, err := QueryMetric(context.Background(), backend, options, testLogger()); !errors.Is(err, unavailable) {
+		t.Fatalf("first QueryMetric() error = %v, want %v", err, unavailable)
+	}
+	// The last response repeats once the script is exhausted.
+	for i := 0; i < 2; i++ {
+		if got, err := QueryMetric(context.Background(), backend, options, testLogger()); err != nil || got != 5 {
+			t.Fatalf("QueryMetric() = %v, %v, want 5", got, err)
+		}
+	}
+
+	options.Query = "unknown"
+	options.NaNStrategy = NaNStrategyZero
+	if got, err := QueryMetric(context.Background(), backend, options, testLogger()); err != nil || got != 0 {
+		t.Fatalf("QueryMetric() of unscripted query = %v, %v, want 0 with zero strategy", got, err)
+	}
+}
@@ -0,0 +1,52 @@
+package metrics
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestExtractValidPointsTimestampAwareStrategies(t *testing.T) {
+	// Irregular sampling: the gap at 40s sits a quarter of the way from 30s to 70s.
+	timestamps := []int64{0, 30000, 40000, 70000, 130000}
+	values := []Value{{Kind: ValueNaN}, FiniteValue(10), {Kind: ValueMissing}, FiniteValue(50), {Kind: ValueNaN}}
+
+	tests := []struct {
+		strategy NaNStrategy
+		maxAge   time.Duration
+		want     []Point
+	}{
+		{NaNStrategyInterpolate, 0, []Point{{30000, 10}, {40000, 20}, {70000, 50}}},
+		{NaNStrategyNextValid, 0, []Point{{0, 10}, {30000, 10}, {40000, 50}, {70000, 50}}},
+		{NaNStrategyLastValid, 0, []Point{{30000, 10}, {40000, 10}, {70000, 50}, {130000, 50}}},
+		{NaNStrategyLastValidWithin, 30 * time.Second, []Point{{30000, 10}, {40000, 10}, {70000, 50}}},
+		{NaNStrategyLastValidWithin, time.Minute, []Point{{30000, 10}, {40000, 10}, {70000, 50}, {130000, 50}}},
+	}
+
+	for _, tt := range tests {
+		got, _ := ExtractValidPoints(timestamps, values, tt.strategy, tt.strategy, tt.maxAge)
+		if len(got) != len(tt.want) {
+			t.Fatalf("%s(%v) = %v, want %v", tt.strategy, tt.maxAge, got, tt.want)
+		}
+		for i := range got {
+			if got[i].Timestamp != tt.want[i].Timestamp || math.Abs(got[i].Value-tt.want[i].Value) > 1e-9 {
+				t.Fatalf("%s(%v) = %v, want %v", tt.strategy, tt.maxAge, got, tt.want)
+			}
+		}
+	}
+}
+
+func TestParseNaNStrategyTimestampAware(t *testing.T) {
+	for input, want := range map[string]NaNStrategy{
+		"interpolate":     NaNStrategyInterpolate,
+		"nextValid":       NaNStrategyNextValid,
+		"lastValidWithin": NaNStrategyLastValidWithin,
+	} {
+		if got := ParseNaNStrategy(input); got != want {
+			t.Fatalf("ParseNaNStrategy(%q) = %q, want %q", input, got, want)
+		}
+	}
+	if got := ParseNaNMaxAge("bogus"); got != defaultNaNMaxAge {
+		t.Fatalf("ParseNaNMaxAge(bogus) = %v, want default", got)
+	}
+}
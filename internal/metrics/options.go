@@ -1,8 +1,10 @@
 package metrics
 
 import (
+	"fmt"
 	"strconv"
 	"strings"
+	"time"
 )
 
 type NaNStrategy string
@@ -12,8 +14,14 @@ const (
 	NaNStrategyZero      NaNStrategy = "zero"
 	NaNStrategyError     NaNStrategy = "error"
 	NaNStrategyLastValid NaNStrategy = "lastValid"
+
+	NaNStrategyLastValidWithin NaNStrategy = "lastValidWithin"
+	NaNStrategyNextValid       NaNStrategy = "nextValid"
+	NaNStrategyInterpolate     NaNStrategy = "interpolate"
 )
 
+const defaultNaNMaxAge = time.Minute
+
 type AggregationMethod string
 
 const (
@@ -22,6 +30,10 @@ const (
 	AggregationMax  AggregationMethod = "max"
 	AggregationMin  AggregationMethod = "min"
 	AggregationLast AggregationMethod = "last"
+
+	AggregationLatest   AggregationMethod = "latest"
+	AggregationEarliest AggregationMethod = "earliest"
+	AggregationTWA      AggregationMethod = "twa"
 )
 
 type DownsamplingMode string
@@ -30,6 +42,7 @@ const (
 	DownsamplingMaxPoints    DownsamplingMode = "maxPoints"
 	DownsamplingGridInterval DownsamplingMode = "gridInterval"
 	DownsamplingDisabled     DownsamplingMode = "disabled"
+	DownsamplingAuto         DownsamplingMode = "auto"
 	DownsamplingNone         DownsamplingMode = "none"
 )
 
@@ -40,18 +53,33 @@ type DownsamplingOptions struct {
 	MaxPoints       int              // For maxPoints mode (≥10)
 	GridInterval    int64            // For gridInterval mode (milliseconds, >0)
 	Disabled        bool             // For disabled mode
+	Resolution      int              // For auto mode: desired points per time window
 	HasSettings     bool             // Whether any downsampling settings were provided
 }
 
 type QueryOptions struct {
-	Query                 string
-	FolderID              string
-	NaNStrategy           NaNStrategy
-	AggregationMethod     AggregationMethod
-	TimeSeriesAggregation AggregationMethod
-	TimeWindow            string
-	TimeWindowOffset      string
-	Downsampling          DownsamplingOptions
+	Query                  string
+	FolderID               string
+	FallbackQuery          string    // Evaluated when Query produces no data
+	FallbackFolderID       string    // Defaults to FolderID
+	ScaledObject           string    // Caller identity for fair queuing under rate limits
+	Now                    time.Time // End of the query's time range before the offset; zero means the current time
+	NaNStrategy            NaNStrategy
+	InfStrategy            NaNStrategy
+	NaNMaxAge              time.Duration
+	AggregationMethod      AggregationMethod
+	TimeSeriesAggregation  AggregationMethod
+	CrossSeriesAggregation AggregationMethod // Enables pointwise mode: aggregate across aligned series first
+	AlignmentTolerance     time.Duration
+	TimeWindow             string
+	TimeWindowOffset       string
+	Downsampling           DownsamplingOptions
+	Transform              ValueTransform
+	Forecast               ForecastOptions
+	Outliers               OutlierOptions
+	Histogram              HistogramOptions
+	SLO                    SLOOptions
+	Baseline               BaselineOptions
 }
 
 func ParseNaNStrategy(s string) NaNStrategy {
@@ -64,11 +92,34 @@ func ParseNaNStrategy(s string) NaNStrategy {
 		return NaNStrategyError
 	case "lastvalid", "last_valid":
 		return NaNStrategyLastValid
+	case "lastvalidwithin", "last_valid_within":
+		return NaNStrategyLastValidWithin
+	case "nextvalid", "next_valid":
+		return NaNStrategyNextValid
+	case "interpolate", "linear":
+		return NaNStrategyInterpolate
 	default:
 		return NaNStrategyError
 	}
 }
 
+// ParseNaNMaxAge parses how far lastValidWithin may carry a value forward.
+func ParseNaNMaxAge(s string) time.Duration {
+	if d, err := time.ParseDuration(strings.TrimSpace(s)); err == nil && d > 0 {
+		return d
+	}
+	return defaultNaNMaxAge
+}
+
+// ParseInfStrategy parses the strategy for infinite values, falling back to
+// the NaN strategy when none is set.
+func ParseInfStrategy(s string, nanStrategy NaNStrategy) NaNStrategy {
+	if strings.TrimSpace(s) == "" {
+		return nanStrategy
+	}
+	return ParseNaNStrategy(s)
+}
+
 func ParseOptionalAggregationMethod(s string) AggregationMethod {
 	if strings.TrimSpace(s) == "" {
 		return ""
@@ -86,6 +137,12 @@ func ParseAggregationMethod(s string) AggregationMethod {
 		return AggregationMin
 	case "last":
 		return AggregationLast
+	case "latest", "newest":
+		return AggregationLatest
+	case "earliest", "first", "oldest":
+		return AggregationEarliest
+	case "twa", "timeweightedavg", "time_weighted_avg":
+		return AggregationTWA
 	case "avg", "average", "mean":
 		return AggregationAvg
 	default:
@@ -93,7 +150,10 @@ func ParseAggregationMethod(s string) AggregationMethod {
 	}
 }
 
-func ParseDownsamplingOptions(metadata map[string]string) DownsamplingOptions {
+// ParseDownsamplingOptions reads the downsampling.* keys. Combining
+// downsampling.mode auto with an explicit maxPoints, gridInterval or disabled
+// is an error.
+func ParseDownsamplingOptions(metadata map[string]string) (DownsamplingOptions, error) {
 	opts := DownsamplingOptions{
 		GridAggregation: parseGridAggregation(metadata["downsampling.gridAggregation"]),
 		GapFilling:      parseGapFilling(metadata["downsampling.gapFilling"]),
@@ -106,6 +166,8 @@ func ParseDownsamplingOptions(metadata map[string]string) DownsamplingOptions {
 		"downsampling.maxPoints",
 		"downsampling.gridInterval",
 		"downsampling.disabled",
+		"downsampling.mode",
+		"downsampling.resolution",
 	}
 
 	for _, key := range downsamplingKeys {
@@ -119,7 +181,7 @@ func ParseDownsamplingOptions(metadata map[string]string) DownsamplingOptions {
 		return DownsamplingOptions{
 			Mode:        DownsamplingNone,
 			HasSettings: false,
-		}
+		}, nil
 	}
 
 	modes := []struct {
@@ -133,10 +195,18 @@ func ParseDownsamplingOptions(metadata map[string]string) DownsamplingOptions {
 	}
 
 	activeMode := ""
+	if strings.EqualFold(strings.TrimSpace(metadata["downsampling.mode"]), string(DownsamplingAuto)) {
+		activeMode = "downsampling.mode"
+		opts.Mode = DownsamplingAuto
+		opts.Resolution = parseResolution(metadata["downsampling.resolution"])
+	}
 	for _, m := range modes {
 		if metadata[m.key] != "" {
+			if opts.Mode == DownsamplingAuto {
+				return DownsamplingOptions{}, fmt.Errorf("downsampling.mode auto cannot be combined with %s", m.key)
+			}
 			if activeMode != "" {
-				return getErrorDownsampling()
+				return getErrorDownsampling(), nil
 			}
 			activeMode = m.key
 			opts.Mode = m.mode
@@ -151,12 +221,14 @@ func ParseDownsamplingOptions(metadata map[string]string) DownsamplingOptions {
 		}
 	}
 
+	// Only gridAggregation or gapFilling were given: pick the grid
+	// automatically rather than an arbitrary point count.
 	if activeMode == "" && opts.HasSettings {
-		opts.Mode = DownsamplingMaxPoints
-		opts.MaxPoints = 10
+		opts.Mode = DownsamplingAuto
+		opts.Resolution = parseResolution(metadata["downsampling.resolution"])
 	}
 
-	return opts
+	return opts, nil
 }
 
 func parseGridAggregation(s string) string {
@@ -193,6 +265,13 @@ func parseMaxPoints(s string) int {
 	return 10
 }
 
+func parseResolution(s string) int {
+	if val, err := strconv.Atoi(strings.TrimSpace(s)); err == nil && val > 0 {
+		return val
+	}
+	return defaultDownsamplingResolution
+}
+
 func parseGridInterval(s string) int64 {
 	if s == "" {
 		return 0
@@ -16,3 +16,24 @@ func TestParseOptionalAggregationMethod(t *testing.T) {
 		t.Fatalf("avg optional aggregation = %q, want %q", got, AggregationAvg)
 	}
 }
+
+func TestParseDownsamplingOptionsAuto(t *testing.T) {
+	opts, err := ParseDownsamplingOptions(map[string]string{
+		"downsampling.mode":       "auto",
+		"downsampling.resolution": "20",
+	})
+	if err != nil || opts.Mode != DownsamplingAuto || opts.Resolution != 20 || !opts.HasSettings {
+		t.Fatalf("auto mode = %+v", opts)
+	}
+
+	opts, err = ParseDownsamplingOptions(map[string]string{"downsampling.gridAggregation": "avg"})
+	if err != nil || opts.Mode != DownsamplingAuto || opts.Resolution != defaultDownsamplingResolution || opts.MaxPoints != 0 {
+		t.Fatalf("gridAggregation only = %+v, want auto grid", opts)
+	}
+
+	for _, key := range []string{"downsampling.maxPoints", "downsampling.gridInterval", "downsampling.disabled"} {
+		if _, err := ParseDownsamplingOptions(map[string]string{"downsampling.mode": "auto", key: "100"}); err == nil {
+			t.Errorf("auto mode with %s should fail", key)
+		}
+	}
+}
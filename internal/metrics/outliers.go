@@ -0,0 +1,205 @@
+package metrics
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+type OutlierMethod string
+
+const (
+	OutlierNone OutlierMethod = ""
+	OutlierMAD  OutlierMethod = "mad"
+	OutlierIQR  OutlierMethod = "iqr"
+	OutlierTrim OutlierMethod = "trim"
+)
+
+type OutlierScope string
+
+const (
+	OutlierScopeSeries OutlierScope = "series"
+	OutlierScopeAll    OutlierScope = "all"
+)
+
+// minOutlierSamples is the smallest sample the filters are applied to;
+// smaller samples are passed through unchanged.
+const minOutlierSamples = 4
+
+var defaultOutlierThresholds = map[OutlierMethod]float64{
+	OutlierMAD:  3.5,
+	OutlierIQR:  1.5,
+	OutlierTrim: 0.1,
+}
+
+type OutlierOptions struct {
+	Method OutlierMethod
+	// Threshold is the modified z-score cutoff for mad, the fence multiplier
+	// for iqr, and the fraction trimmed from each end for trim.
+	Threshold float64
+	Scope     OutlierScope
+}
+
+func (o OutlierOptions) Enabled() bool {
+	return o.Method != OutlierNone
+}
+
+func ParseOutlierOptions(metadata map[string]string) (OutlierOptions, error) {
+	var opts OutlierOptions
+
+	switch strings.ToLower(strings.TrimSpace(metadata["outlierFilter"])) {
+	case "", "none":
+		return OutlierOptions{}, nil
+	case "mad":
+		opts.Method = OutlierMAD
+	case "iqr":
+		opts.Method = OutlierIQR
+	case "trim", "trimmed", "trimmedmean":
+		opts.Method = OutlierTrim
+	default:
+		return OutlierOptions{}, fmt.Errorf("unsupported outlierFilter: %q", metadata["outlierFilter"])
+	}
+
+	opts.Threshold = defaultOutlierThresholds[opts.Method]
+	if s := metadata["outlierThreshold"]; s != "" {
+		v, err := parseFiniteFloat("outlierThreshold", s)
+		if err != nil {
+			return OutlierOptions{}, err
+		}
+		if v <= 0 || (opts.Method == OutlierTrim && v >= 0.5) {
+			return OutlierOptions{}, fmt.Errorf("outlierThreshold out of range for %s: %q", opts.Method, s)
+		}
+		opts.Threshold = v
+	}
+
+	switch strings.ToLower(strings.TrimSpace(metadata["outlierScope"])) {
+	case "", "series":
+		opts.Scope = OutlierScopeSeries
+	case "all", "across":
+		opts.Scope = OutlierScopeAll
+	default:
+		return OutlierOptions{}, fmt.Errorf("unsupported outlierScope: %q", metadata["outlierScope"])
+	}
+
+	return opts, nil
+}
+
+// FilterOutliers removes outliers from each series, judging each series on its
+// own or all series together depending on the scope. It returns the filtered
+// series and the number of rejected points.
+func FilterOutliers(series []Series, opts OutlierOptions) ([]Series, int) {
+	if !opts.Enabled() {
+		return series, 0
+	}
+
+	var all []float64
+	if opts.Scope == OutlierScopeAll {
+		for _, s := range series {
+			all = append(all, pointValues(s.Points)...)
+		}
+	}
+	allKeep := outlierMask(all, opts)
+
+	filtered := make([]Series, 0, len(series))
+	rejected, offset := 0, 0
+	for _, s := range series {
+		var keep []bool
+		if opts.Scope == OutlierScopeAll {
+			keep = allKeep[offset : offset+len(s.Points)]
+			offset += len(s.Points)
+		} else {
+			keep = outlierMask(pointValues(s.Points), opts)
+		}
+
+		kept := applyMask(s.Points, keep)
+		rejected += len(s.Points) - len(kept)
+		if len(kept) > 0 {
+			s.Points = kept
+			filtered = append(filtered, s)
+		}
+	}
+	return filtered, rejected
+}
+
+func applyMask(points []Point, keep []bool) []Point {
+	kept := make([]Point, 0, len(points))
+	for i, p := range points {
+		if keep[i] {
+			kept = append(kept, p)
+		}
+	}
+	return kept
+}
+
+func outlierMask(values []float64, opts OutlierOptions) []bool {
+	keep := make([]bool, len(values))
+	for i := range keep {
+		keep[i] = true
+	}
+	if len(values) < minOutlierSamples {
+		return keep
+	}
+
+	switch opts.Method {
+	case OutlierMAD:
+		median := quantile(values, 0.5)
+		deviations := make([]float64, len(values))
+		for i, v := range values {
+			deviations[i] = math.Abs(v - median)
+		}
+		// 0.6745 makes the MAD consistent with the standard deviation for
+		// normal data. A zero MAD falls back to the mean absolute deviation.
+		scale := quantile(deviations, 0.5) / 0.6745
+		if scale == 0 {
+			sum := 0.0
+			for _, d := range deviations {
+				sum += d
+			}
+			scale = sum / float64(len(deviations)) * 1.2533
+		}
+		if scale == 0 {
+			return keep
+		}
+		for i, d := range deviations {
+			keep[i] = d/scale <= opts.Threshold
+		}
+
+	case OutlierIQR:
+		q1, q3 := quantile(values, 0.25), quantile(values, 0.75)
+		low, high := q1-opts.Threshold*(q3-q1), q3+opts.Threshold*(q3-q1)
+		for i, v := range values {
+			keep[i] = v >= low && v <= high
+		}
+
+	case OutlierTrim:
+		trim := int(math.Floor(float64(len(values)) * opts.Threshold))
+		order := make([]int, len(values))
+		for i := range order {
+			order[i] = i
+		}
+		sort.SliceStable(order, func(a, b int) bool { return values[order[a]] < values[order[b]] })
+		for i := 0; i < trim; i++ {
+			keep[order[i]] = false
+			keep[order[len(order)-1-i]] = false
+		}
+	}
+
+	return keep
+}
+
+// quantile returns the q-th quantile using linear interpolation between
+// closest ranks.
+func quantile(values []float64, q float64) float64 {
+	sorted := make([]float64, len(values))
+	copy(sorted, values)
+	sort.Float64s(sorted)
+
+	pos := q * float64(len(sorted)-1)
+	lower := int(math.Floor(pos))
+	upper := int(math.Ceil(pos))
+	if lower == upper {
+		return sorted[lower]
+	}
+	return sorted[lower] + (sorted[upper]-sorted[lower])*(pos-float64(lower))
+}
@@ -0,0 +1,75 @@
+package metrics
+
+import (
+	"strings"
+	"testing"
+)
+
+func seriesOf(values ...float64) []Point {
+	points := make([]Point, len(values))
+	for i, v := range values {
+		points[i] = Point{Timestamp: int64(i) * 60000, Value: v}
+	}
+	return points
+}
+
+func TestFilterOutliers(t *testing.T) {
+	spiky := seriesOf(10, 11, 9, 10, 12, 5000, 10, 11)
+
+	tests := []struct {
+		name         string
+		opts         OutlierOptions
+		wantRejected int
+		wantMax      float64
+	}{
+		{name: "mad", opts: OutlierOptions{Method: OutlierMAD, Threshold: 3.5, Scope: OutlierScopeSeries}, wantRejected: 1, wantMax: 12},
+		{name: "iqr", opts: OutlierOptions{Method: OutlierIQR, Threshold: 1.5, Scope: OutlierScopeSeries}, wantRejected: 1, wantMax: 12},
+		{name: "trim", opts: OutlierOptions{Method: OutlierTrim, Threshold: 0.125, Scope: OutlierScopeSeries}, wantRejected: 2, wantMax: 12},
+		{name: "disabled", opts: OutlierOptions{}, wantRejected: 0, wantMax: 5000},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filtered, rejected := FilterOutliers([]Series{{Points: spiky}}, tt.opts)
+			if rejected != tt.wantRejected {
+				t.Fatalf("rejected = %d, want %d", rejected, tt.wantRejected)
+			}
+			got, _ := Aggregate(pointValues(filtered[0].Points), AggregationMax)
+			if got != tt.wantMax {
+				t.Fatalf("max after filter = %v, want %v", got, tt.wantMax)
+			}
+		})
+	}
+}
+
+func TestFilterOutliersScope(t *testing.T) {
+	// Each series is consistent on its own, but b is an outlier next to a, c and d.
+	series := []Series{{Points: seriesOf(10, 11, 12, 13)}, {Points: seriesOf(900, 910, 920, 930)}, {Points: seriesOf(11, 12, 13, 14)}, {Points: seriesOf(9, 10, 11, 12)}}
+
+	_, rejected := FilterOutliers(series, OutlierOptions{Method: OutlierIQR, Threshold: 1.5, Scope: OutlierScopeSeries})
+	if rejected != 0 {
+		t.Fatalf("per-series rejected = %d, want 0", rejected)
+	}
+
+	filtered, rejected := FilterOutliers(series, OutlierOptions{Method: OutlierIQR, Threshold: 1.5, Scope: OutlierScopeAll})
+	if rejected != 4 || len(filtered) != 3 {
+		t.Fatalf("across-series rejected = %d, series left = %d, want 4 and 3", rejected, len(filtered))
+	}
+}
+
+func TestParseOutlierOptions(t *testing.T) {
+	opts, err := ParseOutlierOptions(map[string]string{"outlierFilter": "mad"})
+	if err != nil || opts.Threshold != 3.5 || opts.Scope != OutlierScopeSeries {
+		t.Fatalf("ParseOutlierOptions(mad) = %+v, %v", opts, err)
+	}
+
+	for _, metadata := range []map[string]string{
+		{"outlierFilter": "zscore"},
+		{"outlierFilter": "trim", "outlierThreshold": "0.5"},
+		{"outlierFilter": "iqr", "outlierScope": "zone"},
+	} {
+		if _, err := ParseOutlierOptions(metadata); err == nil || !strings.Contains(err.Error(), "outlier") {
+			t.Fatalf("ParseOutlierOptions(%v) error = %v, want error", metadata, err)
+		}
+	}
+}
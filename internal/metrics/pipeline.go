@@ -0,0 +1,288 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"keda-external-scaler-yc-monitoring/internal/logger"
+)
+
+// ErrNoData is returned when a query matches no series or leaves no valid
+// values and no strategy or fallback provides a value.
+var ErrNoData = errors.New("no valid metric data available")
+
+// QueryMetric reads the query from backend and reduces the series to a single
+// value: invalid points are handled according to the NaN and Inf strategies,
+// outliers are filtered, and the remaining points are combined and
+// transformed.
+func QueryMetric(ctx context.Context, backend Backend, options QueryOptions, logger *logger.Logger) (float64, error) {
+	if options.Baseline.Enabled() {
+		return queryWithBaseline(ctx, backend, options, logger)
+	}
+	if options.SLO.Enabled {
+		return queryBurnRate(ctx, backend, options, logger)
+	}
+	return queryValue(ctx, backend, options, logger)
+}
+
+func queryValue(ctx context.Context, backend Backend, options QueryOptions, logger *logger.Logger) (float64, error) {
+	logger.Debug("Querying metric: query=%s, folder=%s, hasDownsampling=%t, timeWindowOffset=%s",
+		options.Query, options.FolderID, options.Downsampling.HasSettings, options.TimeWindowOffset)
+
+	timeWindow := 5 * time.Minute
+	if options.TimeWindow != "" {
+		if duration, err := time.ParseDuration(options.TimeWindow); err == nil {
+			timeWindow = duration
+			logger.Debug("Using custom time window: %v", timeWindow)
+		} else {
+			logger.Warn("Invalid timeWindow format '%s', using default 5m", options.TimeWindow)
+		}
+	}
+
+	timeWindowOffset := 30 * time.Second
+	if options.TimeWindowOffset != "" {
+		if duration, err := time.ParseDuration(options.TimeWindowOffset); err == nil {
+			timeWindowOffset = duration
+			logger.Debug("Using custom time window offset: %v", timeWindowOffset)
+		} else {
+			logger.Warn("Invalid timeWindowOffset format '%s', using default 30s", options.TimeWindowOffset)
+		}
+	}
+
+	now := options.Now
+	if now.IsZero() {
+		now = time.Now()
+	}
+	now = now.UTC()
+	endTime := now.Add(-timeWindowOffset)
+	startTime := endTime.Add(-timeWindow)
+
+	if options.Downsampling.Mode == DownsamplingAuto {
+		interval := AutoGridInterval(timeWindow, options.Downsampling.Resolution)
+		startTime, endTime = AlignToGrid(startTime, endTime, interval)
+		options.Downsampling.GridInterval = interval.Milliseconds()
+		logger.Debug("Auto downsampling: gridInterval=%v for window %v at resolution %d, range aligned to %s - %s",
+			interval, timeWindow, options.Downsampling.Resolution,
+			startTime.Format(time.RFC3339), endTime.Format(time.RFC3339))
+	}
+
+	logger.Debug("Time range: %s to %s (window: %v, offset: %v)",
+		startTime.Format("2006-01-02T15:04:05Z"), endTime.Format("2006-01-02T15:04:05Z"), timeWindow, timeWindowOffset)
+
+	rawSeries, err := backend.Read(ctx, ReadRequest{
+		Query:        options.Query,
+		FolderID:     options.FolderID,
+		From:         startTime,
+		To:           endTime,
+		Downsampling: options.Downsampling,
+		ScaledObject: options.ScaledObject,
+	}, logger)
+	if err != nil {
+		return 0, err
+	}
+
+	var series []Series
+	var counts ValueCounts
+
+	for i, raw := range rawSeries {
+		logger.Debug("Processing metric %d: name=%s, labels=%v", i, raw.Name, raw.Labels)
+
+		points, seriesCounts := ExtractValidPoints(
+			raw.Timestamps,
+			raw.Values,
+			options.NaNStrategy,
+			options.InfStrategy,
+			options.NaNMaxAge,
+		)
+		counts.Add(seriesCounts)
+
+		logger.Debug("Extracted %d valid values from metric %d (NaN: %d, Inf: %d, missing: %d)",
+			len(points), i, seriesCounts.NaN, seriesCounts.Inf, seriesCounts.Missing)
+
+		if len(points) > 0 {
+			series = append(series, Series{Name: raw.Name, Labels: raw.Labels, Points: points})
+		}
+	}
+
+	series, rejected := FilterOutliers(series, options.Outliers)
+	if rejected > 0 {
+		logger.Debug("Outlier filter (%s, threshold %g, scope %s) rejected %d points",
+			options.Outliers.Method, options.Outliers.Threshold, options.Outliers.Scope, rejected)
+	}
+
+	allPoints, err := reduceSeries(series, options, logger)
+	if err != nil {
+		logger.Error("Series processing failed: %v", err)
+		return 0, err
+	}
+	allValues := pointValues(allPoints)
+
+	logger.LogClientProcessing(counts.Total, counts.NaN, counts.Inf, counts.Missing, rejected, len(allValues), allValues, options.NaNStrategy)
+
+	if len(allValues) == 0 {
+		if options.NaNStrategy == NaNStrategyError && counts.NaN+counts.Missing > 0 {
+			logger.Error("All values are NaN or missing with error strategy")
+			return 0, fmt.Errorf("all metric values are NaN or missing")
+		}
+		if options.InfStrategy == NaNStrategyError && counts.Inf > 0 {
+			logger.Error("All values are infinite or NaN with error strategy")
+			return 0, fmt.Errorf("all metric values are infinite or NaN")
+		}
+
+		logger.Warn("No valid values found after processing (total: %d, NaN: %d, Inf: %d, missing: %d)",
+			counts.Total, counts.NaN, counts.Inf, counts.Missing)
+
+		if options.FallbackQuery != "" {
+			return queryFallback(ctx, backend, options, logger)
+		}
+
+		if options.NaNStrategy == NaNStrategyZero {
+			logger.Info("No data available with zero strategy, returning 0")
+			return applyTransform(0, options.Transform, logger), nil
+		}
+
+		return 0, ErrNoData
+	}
+
+	result, err := AggregatePoints(allPoints, options.AggregationMethod)
+	if err != nil {
+		logger.Error("Aggregation failed: %v", err)
+		return 0, err
+	}
+
+	logger.LogAggregation(string(options.AggregationMethod), allValues, result)
+
+	result = applyTransform(result, options.Transform, logger)
+	logger.Info("Final metric value: %f (processed %d values, %d were NaN, %d infinite, %d missing)",
+		result, counts.Total, counts.NaN, counts.Inf, counts.Missing)
+
+	return result, nil
+}
+
+// queryFallback evaluates the fallback query with the same options when the
+// primary query produced no data.
+func queryFallback(ctx context.Context, backend Backend, options QueryOptions, logger *logger.Logger) (float64, error) {
+	fallback := options
+	fallback.Query = options.FallbackQuery
+	fallback.FallbackQuery = ""
+	fallback.FallbackFolderID = ""
+	if options.FallbackFolderID != "" {
+		fallback.FolderID = options.FallbackFolderID
+	}
+
+	logger.Warn("Primary query returned no data, evaluating fallback query: query=%s, folder=%s",
+		fallback.Query, fallback.FolderID)
+
+	result, err := queryValue(ctx, backend, fallback, logger)
+	if err != nil {
+		return 0, fmt.Errorf("primary query returned no data and fallback query failed: %w", err)
+	}
+
+	logger.Info("Metric value %f produced by fallback query (folder %s)", result, fallback.FolderID)
+	return result, nil
+}
+
+func applyTransform(value float64, transform ValueTransform, logger *logger.Logger) float64 {
+	if transform.IsIdentity() {
+		return value
+	}
+
+	result := transform.Apply(value)
+	logger.Debug("Value transform (unit=%q, offset=%g): %f -> %f",
+		transform.Unit, transform.Offset, value, result)
+
+	return result
+}
+
+// reduceSeries turns the extracted series into the points that
+// aggregationMethod combines into the final result. A series reduced to a
+// single value keeps the timestamp of its latest sample, or the predicted
+// time for forecasts.
+func reduceSeries(series []Series, options QueryOptions, logger *logger.Logger) ([]Point, error) {
+	if options.Histogram.Enabled {
+		return histogramPoints(series, options, logger)
+	}
+
+	if options.CrossSeriesAggregation != "" && len(series) > 0 {
+		grid := alignmentGrid(options)
+		combined, err := AlignSeries(seriesPoints(series), options.CrossSeriesAggregation, options.AlignmentTolerance, grid)
+		if err != nil {
+			return nil, err
+		}
+		logger.Debug("Cross-series aggregation (%s, %s) of %d series: %d aligned points",
+			options.CrossSeriesAggregation, describeAlignment(options.AlignmentTolerance, grid), len(series), len(combined))
+		series = []Series{{Name: "aligned", Points: combined}}
+	}
+
+	var allPoints []Point
+	for i, s := range series {
+		points := s.Points
+		if options.Forecast.Enabled() {
+			predicted, err := Forecast(points, options.Forecast)
+			if err == nil {
+				allPoints = append(allPoints, Point{
+					Timestamp: latestTimestamp(points) + options.Forecast.Horizon.Milliseconds(),
+					Value:     predicted,
+				})
+				logger.Debug("Forecast (%s, horizon %v) for series %d over %d points: %f",
+					options.Forecast.Method, options.Forecast.Horizon, i, len(points), predicted)
+			}
+			continue
+		}
+
+		if options.TimeSeriesAggregation != "" && options.CrossSeriesAggregation == "" {
+			tsValue, err := AggregatePoints(points, options.TimeSeriesAggregation)
+			if err == nil {
+				allPoints = append(allPoints, Point{Timestamp: latestTimestamp(points), Value: tsValue})
+				logger.Debug("Time series aggregation (%s): %v -> %f",
+					options.TimeSeriesAggregation, pointValues(points), tsValue)
+			}
+		} else {
+			allPoints = append(allPoints, points...)
+		}
+	}
+
+	return allPoints, nil
+}
+
+// histogramPoints reduces bucket series to a single quantile estimate. Each
+// bucket series is first reduced over time with timeSeriesAggregation, or
+// summed when it is not set.
+func histogramPoints(series []Series, options QueryOptions, logger *logger.Logger) ([]Point, error) {
+	if len(series) == 0 {
+		return nil, nil
+	}
+
+	method := options.TimeSeriesAggregation
+	if method == "" {
+		method = AggregationSum
+	}
+
+	buckets, skipped, err := BuildHistogram(series, options.Histogram, method)
+	if err != nil {
+		return nil, err
+	}
+	if len(skipped) > 0 {
+		logger.Warn("Ignoring %d series without a valid %q bucket label: %v",
+			len(skipped), options.Histogram.BucketLabel, skipped)
+	}
+	logger.Debug("Histogram buckets (label=%s, cumulative=%t): %+v",
+		options.Histogram.BucketLabel, options.Histogram.Cumulative, buckets)
+
+	value, err := HistogramQuantile(options.Histogram.Quantile, buckets, options.Histogram.Cumulative)
+	if err != nil {
+		logger.Warn("Cannot compute histogram quantile: %v", err)
+		return nil, nil
+	}
+	logger.Debug("Histogram quantile %g: %f", options.Histogram.Quantile, value)
+
+	latest := int64(0)
+	for _, s := range series {
+		if ts := latestTimestamp(s.Points); ts > latest {
+			latest = ts
+		}
+	}
+	return []Point{{Timestamp: latest, Value: value}}, nil
+}
@@ -0,0 +1,162 @@
+package metrics
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+
+	"keda-external-scaler-yc-monitoring/internal/logger"
+)
+
+type retryPolicy struct {
+	maxAttempts    int
+	initialBackoff time.Duration
+	maxBackoff     time.Duration
+}
+
+func isRetryableStatus(status int) bool {
+	switch status {
+	case http.StatusTooManyRequests, http.StatusInternalServerError, http.StatusBadGateway,
+		http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// backoff returns the delay before the given retry (1-based): exponential
+// growth capped at maxBackoff, with equal jitter so that scalers polling in
+// lockstep spread out.
+func (p retryPolicy) backoff(retry int) time.Duration {
+	d := p.initialBackoff
+	for i := 1; i < retry && d < p.maxBackoff; i++ {
+		d *= 2
+	}
+	if p.maxBackoff > 0 && d > p.maxBackoff {
+		d = p.maxBackoff
+	}
+	if d <= 0 {
+		return 0
+	}
+	half := d / 2
+	return half + time.Duration(rand.Int63n(int64(d-half)+1))
+}
+
+// parseRetryAfter reads a Retry-After header given either in seconds or as an
+// HTTP date.
+func parseRetryAfter(header string, now time.Time) (time.Duration, bool) {
+	if header == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(header); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if t, err := http.ParseTime(header); err == nil {
+		if d := t.Sub(now); d > 0 {
+			return d, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
+
+// post sends the read request, retrying transport errors and transient
+// statuses. Retries stop once another attempt would not fit before the
+// context deadline.
+//
+// A 200 response is decoded into out. The returned body holds the error
+// response for other statuses, and the raw response when debug logging is on.
+func (c *Client) post(ctx context.Context, url, token string, payload []byte, req ReadRequest, out *MetricResponse, logger *logger.Logger) (int, []byte, error) {
+	attempts := c.retry.maxAttempts
+	if attempts < 1 {
+		attempts = 1
+	}
+
+	for attempt := 1; ; attempt++ {
+		release, err := c.acquire(ctx, req, logger)
+		if err != nil {
+			return 0, nil, err
+		}
+		status, body, retryAfter, err := c.postOnce(ctx, url, token, payload, out, logger.DebugEnabled())
+		release()
+		// A 200 whose body fails to decode is not retried.
+		if (err == nil || status == http.StatusOK) && !isRetryableStatus(status) {
+			return status, body, err
+		}
+		if attempt >= attempts {
+			return status, body, err
+		}
+
+		delay := c.retry.backoff(attempt)
+		if d, ok := parseRetryAfter(retryAfter, time.Now()); ok {
+			delay = d
+		}
+		deadline, hasDeadline := ctx.Deadline()
+		if hasDeadline && time.Now().Add(delay).After(deadline) {
+			logger.Warn("Not retrying Monitoring API request: backoff %v exceeds the request deadline", delay)
+			return status, body, err
+		}
+		// Without a deadline only the per-attempt timeout bounds the call, so
+		// a long Retry-After must not hold it for longer than that.
+		if limit := c.maxRetryDelay(); !hasDeadline && delay > limit {
+			logger.Debug("Capping Monitoring API retry delay of %v at %v", delay, limit)
+			delay = limit
+		}
+
+		if err != nil {
+			logger.Warn("Monitoring API request failed (attempt %d/%d), retrying in %v: %v", attempt, attempts, delay, err)
+		} else {
+			logger.Warn("Monitoring API returned %d (attempt %d/%d), retrying in %v", status, attempt, attempts, delay)
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return 0, nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// maxRetryDelay bounds the wait between attempts of a call without a
+// deadline: the API timeout, or the maximum backoff when it is not set.
+func (c *Client) maxRetryDelay() time.Duration {
+	if c.config != nil && c.config.APITimeout > 0 {
+		return c.config.APITimeout
+	}
+	return c.retry.maxBackoff
+}
+
+func (c *Client) postOnce(ctx context.Context, url, token string, payload []byte, out *MetricResponse, keepBody bool) (int, []byte, string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(payload))
+	if err != nil {
+		return 0, nil, "", fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return 0, nil, "", fmt.Errorf("failed to execute request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	retryAfter := resp.Header.Get("Retry-After")
+	if resp.StatusCode != http.StatusOK {
+		body, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxErrorResponseBytes))
+		if err != nil {
+			return 0, nil, "", fmt.Errorf("failed to read response: %w", err)
+		}
+		return resp.StatusCode, body, retryAfter, nil
+	}
+
+	body, err := c.decodeResponse(resp.Body, out, keepBody)
+	return resp.StatusCode, body, retryAfter, err
+}
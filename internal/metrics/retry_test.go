@@ -0,0 +1,139 @@
+package metrics
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"keda-external-scaler-yc-monitoring/internal/config"
+)
+
+func newRetryingClient(t *testing.T, handler http.HandlerFunc) *Client {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	return newClientWithConfig(staticToken("test-token"), &config.Config{
+		MonitoringEndpoint:  server.URL,
+		APITimeout:          time.Second,
+		RetryMaxAttempts:    3,
+		RetryInitialBackoff: time.Millisecond,
+		RetryMaxBackoff:     5 * time.Millisecond,
+	})
+}
+
+var retryTestOptions = QueryOptions{
+	Query:             "q",
+	FolderID:          "folder",
+	NaNStrategy:       NaNStrategySkip,
+	InfStrategy:       NaNStrategySkip,
+	AggregationMethod: AggregationAvg,
+}
+
+func TestQueryMetricRetriesTransientErrors(t *testing.T) {
+	calls := 0
+	client := newRetryingClient(t, func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls < 3 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.Write([]byte(`{"metrics":[{"name":"m","timeseries":{"timestamps":[1000],"doubleValues":[7]}}]}`))
+	})
+
+	got, err := client.QueryMetric(context.Background(), retryTestOptions, testLogger())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got != 7 || calls != 3 {
+		t.Errorf("got %v after %d calls, want 7 after 3", got, calls)
+	}
+}
+
+func TestQueryMetricDoesNotRetryClientErrors(t *testing.T) {
+	calls := 0
+	client := newRetryingClient(t, func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusForbidden)
+	})
+
+	if _, err := client.QueryMetric(context.Background(), retryTestOptions, testLogger()); err == nil {
+		t.Fatal("expected error")
+	}
+	if calls != 1 {
+		t.Errorf("calls = %d, want 1", calls)
+	}
+}
+
+func TestQueryMetricRetryBoundedByDeadline(t *testing.T) {
+	calls := 0
+	client := newRetryingClient(t, func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("Retry-After", "30")
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	start := time.Now()
+	_, err := client.QueryMetric(ctx, retryTestOptions, testLogger())
+	if err == nil || !strings.Contains(err.Error(), "503") {
+		t.Fatalf("error = %v, want the 503 API error", err)
+	}
+	if calls != 1 || time.Since(start) > 500*time.Millisecond {
+		t.Errorf("calls = %d after %v, want a single fast attempt", calls, time.Since(start))
+	}
+}
+
+func TestQueryMetricRetryAfterCappedWithoutDeadline(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("Retry-After", "3600")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+	client := newClientWithConfig(staticToken("test-token"), &config.Config{
+		MonitoringEndpoint:  server.URL,
+		APITimeout:          20 * time.Millisecond,
+		RetryMaxAttempts:    3,
+		RetryInitialBackoff: time.Millisecond,
+		RetryMaxBackoff:     5 * time.Millisecond,
+	})
+
+	start := time.Now()
+	if _, err := client.QueryMetric(context.Background(), retryTestOptions, testLogger()); err == nil {
+		t.Fatal("expected the 429 API error")
+	}
+	if elapsed := time.Since(start); calls != 3 || elapsed > 2*time.Second {
+		t.Errorf("calls = %d after %v, want 3 attempts with retry delays capped at the API timeout", calls, elapsed)
+	}
+}
+
+func TestRetryBackoff(t *testing.T) {
+	p := retryPolicy{maxAttempts: 5, initialBackoff: 100 * time.Millisecond, maxBackoff: time.Second}
+	for retry, max := range map[int]time.Duration{1: 100 * time.Millisecond, 2: 200 * time.Millisecond, 3: 400 * time.Millisecond, 6: time.Second} {
+		for i := 0; i < 20; i++ {
+			if d := p.backoff(retry); d < max/2 || d > max {
+				t.Fatalf("backoff(%d) = %v, want within [%v, %v]", retry, d, max/2, max)
+			}
+		}
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2026, time.March, 1, 12, 0, 0, 0, time.UTC)
+	if d, ok := parseRetryAfter("3", now); !ok || d != 3*time.Second {
+		t.Errorf("seconds: %v, %v", d, ok)
+	}
+	if d, ok := parseRetryAfter("Sun, 01 Mar 2026 12:00:10 GMT", now); !ok || d != 10*time.Second {
+		t.Errorf("date: %v, %v", d, ok)
+	}
+	if _, ok := parseRetryAfter("soon", now); ok {
+		t.Error("expected invalid header to be ignored")
+	}
+}
@@ -0,0 +1,162 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"keda-external-scaler-yc-monitoring/internal/logger"
+)
+
+type SLOOptions struct {
+	ErrorQuery        string
+	TotalQuery        string
+	Objective         float64         // Target success ratio, e.g. 0.999
+	Windows           []time.Duration // Burn rate is evaluated over each window
+	WindowCombination AggregationMethod
+	AggregationMethod AggregationMethod // Reduces the error and total queries over a window
+	Enabled           bool
+}
+
+func ParseSLOOptions(metadata map[string]string) (SLOOptions, error) {
+	errorQuery := strings.TrimSpace(metadata["slo.errorQuery"])
+	totalQuery := strings.TrimSpace(metadata["slo.totalQuery"])
+	if errorQuery == "" && totalQuery == "" {
+		return SLOOptions{}, nil
+	}
+	if errorQuery == "" || totalQuery == "" {
+		return SLOOptions{}, fmt.Errorf("slo.errorQuery and slo.totalQuery must both be set")
+	}
+
+	opts := SLOOptions{
+		ErrorQuery:        errorQuery,
+		TotalQuery:        totalQuery,
+		WindowCombination: AggregationMin,
+		AggregationMethod: AggregationSum,
+		Enabled:           true,
+	}
+
+	s := metadata["slo.objective"]
+	if s == "" {
+		return SLOOptions{}, fmt.Errorf("slo.objective is required")
+	}
+	objective, err := parseFiniteFloat("slo.objective", s)
+	if err != nil {
+		return SLOOptions{}, err
+	}
+	if objective > 1 && objective < 100 {
+		objective /= 100
+	}
+	if objective <= 0 || objective >= 1 {
+		return SLOOptions{}, fmt.Errorf("slo.objective must be between 0 and 1 (exclusive): %q", s)
+	}
+	opts.Objective = objective
+
+	windows := metadata["slo.windows"]
+	if strings.TrimSpace(windows) == "" {
+		windows = "5m"
+		if metadata["timeWindow"] != "" {
+			windows = metadata["timeWindow"]
+		}
+	}
+	for _, w := range strings.Split(windows, ",") {
+		d, err := time.ParseDuration(strings.TrimSpace(w))
+		if err != nil || d <= 0 {
+			return SLOOptions{}, fmt.Errorf("slo.windows must be a comma-separated list of positive durations: %q", windows)
+		}
+		opts.Windows = append(opts.Windows, d)
+	}
+
+	switch strings.ToLower(strings.TrimSpace(metadata["slo.windowCombination"])) {
+	case "", "min", "all":
+		opts.WindowCombination = AggregationMin
+	case "max", "any":
+		opts.WindowCombination = AggregationMax
+	case "avg":
+		opts.WindowCombination = AggregationAvg
+	default:
+		return SLOOptions{}, fmt.Errorf("unsupported slo.windowCombination: %q", metadata["slo.windowCombination"])
+	}
+
+	if m := metadata["slo.aggregationMethod"]; m != "" {
+		opts.AggregationMethod = ParseAggregationMethod(m)
+	}
+
+	return opts, nil
+}
+
+// BurnRate is how fast the error budget is consumed: 1 means the budget lasts
+// exactly the SLO period.
+func BurnRate(errors, total, objective float64) float64 {
+	if total <= 0 {
+		return 0
+	}
+	return (errors / total) / (1 - objective)
+}
+
+// queryBurnRate evaluates the burn rate over every SLO window and combines
+// them, by default with min so that all windows must be burning.
+func queryBurnRate(ctx context.Context, backend Backend, options QueryOptions, logger *logger.Logger) (float64, error) {
+	slo := options.SLO
+
+	base := options
+	base.SLO = SLOOptions{}
+	base.AggregationMethod = slo.AggregationMethod
+	base.Transform = ValueTransform{}
+	base.Forecast = ForecastOptions{}
+	base.Histogram = HistogramOptions{}
+	// A fallback query would stand in for whichever of the two queries is
+	// empty and turn the ratio into nonsense.
+	base.FallbackQuery = ""
+	base.FallbackFolderID = ""
+
+	burnRates := make([]float64, 0, len(slo.Windows))
+	for _, window := range slo.Windows {
+		sub := base
+		sub.TimeWindow = window.String()
+
+		// An idle service has no total series, and nothing is burning.
+		sub.Query = slo.TotalQuery
+		total, err := queryValue(ctx, backend, sub, logger)
+		if errors.Is(err, ErrNoData) {
+			logger.Debug("SLO total query over %v returned no data, burn rate is 0", window)
+			burnRates = append(burnRates, 0)
+			continue
+		}
+		if err != nil {
+			return 0, fmt.Errorf("SLO total query over %v: %w", window, err)
+		}
+
+		// Error series commonly only exist once something has failed, so
+		// no data alongside traffic means no errors.
+		sub.Query = slo.ErrorQuery
+		errorCount, err := queryValue(ctx, backend, sub, logger)
+		if errors.Is(err, ErrNoData) {
+			logger.Debug("SLO error query over %v returned no data, counting 0 errors", window)
+			errorCount, err = 0, nil
+		}
+		if err != nil {
+			return 0, fmt.Errorf("SLO error query over %v: %w", window, err)
+		}
+
+		burnRate := BurnRate(errorCount, total, slo.Objective)
+		logger.Debug("SLO window %v: errors=%f, total=%f, objective=%g, burnRate=%f",
+			window, errorCount, total, slo.Objective, burnRate)
+		burnRates = append(burnRates, burnRate)
+	}
+
+	result, err := Aggregate(burnRates, slo.WindowCombination)
+	if err != nil {
+		return 0, err
+	}
+	result = applyTransform(result, options.Transform, logger)
+	if math.IsNaN(result) || math.IsInf(result, 0) {
+		return 0, fmt.Errorf("invalid burn rate: %v", result)
+	}
+
+	logger.Info("SLO burn rate: %f (windows %v, combined with %s)", result, slo.Windows, slo.WindowCombination)
+	return result, nil
+}
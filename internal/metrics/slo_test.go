@@ -0,0 +1,178 @@
+package metrics
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestParseSLOOptions(t *testing.T) {
+	opts, err := ParseSLOOptions(map[string]string{
+		"slo.errorQuery": "errors",
+		"slo.totalQuery": "requests",
+		"slo.objective":  "99.9",
+		"slo.windows":    "5m, 1h",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !opts.Enabled || math.Abs(opts.Objective-0.999) > 1e-12 {
+		t.Errorf("got %+v", opts)
+	}
+	if len(opts.Windows) != 2 || opts.Windows[0] != 5*time.Minute || opts.Windows[1] != time.Hour {
+		t.Errorf("windows = %v", opts.Windows)
+	}
+	if opts.WindowCombination != AggregationMin || opts.AggregationMethod != AggregationSum {
+		t.Errorf("defaults = %+v", opts)
+	}
+
+	opts, err = ParseSLOOptions(map[string]string{})
+	if err != nil || opts.Enabled {
+		t.Errorf("empty metadata: %+v, %v", opts, err)
+	}
+
+	for _, metadata := range []map[string]string{
+		{"slo.errorQuery": "errors"},
+		{"slo.errorQuery": "errors", "slo.totalQuery": "requests"},
+		{"slo.errorQuery": "errors", "slo.totalQuery": "requests", "slo.objective": "1"},
+		{"slo.errorQuery": "errors", "slo.totalQuery": "requests", "slo.objective": "0.99", "slo.windows": "5m,x"},
+		{"slo.errorQuery": "errors", "slo.totalQuery": "requests", "slo.objective": "0.99", "slo.windowCombination": "median"},
+	} {
+		if _, err := ParseSLOOptions(metadata); err == nil {
+			t.Errorf("expected error for %v", metadata)
+		}
+	}
+}
+
+func TestBurnRate(t *testing.T) {
+	if got := BurnRate(2, 1000, 0.999); math.Abs(got-2) > 1e-9 {
+		t.Errorf("BurnRate = %v, want 2", got)
+	}
+	if got := BurnRate(5, 0, 0.999); got != 0 {
+		t.Errorf("BurnRate with no traffic = %v, want 0", got)
+	}
+}
+
+func TestQueryMetricBurnRate(t *testing.T) {
+	// Errors are 1% of traffic over 5m but only 0.2% over 1h.
+	counts := map[string]map[time.Duration]float64{
+		"errors":   {5 * time.Minute: 10, time.Hour: 24},
+		"requests": {5 * time.Minute: 1000, time.Hour: 12000},
+	}
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		var q MetricQuery
+		if err := json.NewDecoder(r.Body).Decode(&q); err != nil {
+			t.Fatalf("decode request: %v", err)
+		}
+		from, _ := time.Parse(time.RFC3339, q.FromTime)
+		to, _ := time.Parse(time.RFC3339, q.ToTime)
+		value := counts[q.Query][to.Sub(from)]
+		w.Write([]byte(`{"metrics":[{"name":"m","timeseries":{"timestamps":[1000],"doubleValues":[` +
+			jsonNumber(value) + `]}}]}`))
+	})
+
+	options := QueryOptions{
+		FolderID:          "folder",
+		NaNStrategy:       NaNStrategySkip,
+		InfStrategy:       NaNStrategySkip,
+		AggregationMethod: AggregationAvg,
+		SLO: SLOOptions{
+			ErrorQuery:        "errors",
+			TotalQuery:        "requests",
+			Objective:         0.99,
+			Windows:           []time.Duration{5 * time.Minute, time.Hour},
+			WindowCombination: AggregationMin,
+			AggregationMethod: AggregationSum,
+			Enabled:           true,
+		},
+	}
+
+	got, err := client.QueryMetric(context.Background(), options, testLogger())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if math.Abs(got-0.2) > 1e-9 {
+		t.Errorf("min burn rate = %v, want 0.2", got)
+	}
+
+	options.SLO.WindowCombination = AggregationMax
+	got, err = client.QueryMetric(context.Background(), options, testLogger())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if math.Abs(got-1) > 1e-9 {
+		t.Errorf("max burn rate = %v, want 1", got)
+	}
+}
+
+func jsonNumber(v float64) string {
+	b, _ := json.Marshal(v)
+	return string(b)
+}
+
+func sloTestOptions() QueryOptions {
+	return QueryOptions{
+		FolderID:          "folder",
+		NaNStrategy:       NaNStrategySkip,
+		InfStrategy:       NaNStrategySkip,
+		AggregationMethod: AggregationAvg,
+		SLO: SLOOptions{
+			ErrorQuery:        "errors",
+			TotalQuery:        "requests",
+			Objective:         0.99,
+			Windows:           []time.Duration{5 * time.Minute},
+			WindowCombination: AggregationMin,
+			AggregationMethod: AggregationSum,
+			Enabled:           true,
+		},
+	}
+}
+
+func TestQueryMetricBurnRateWithoutErrorSeries(t *testing.T) {
+	backend := NewMemoryBackend()
+	backend.Set("requests", RawSeries{Timestamps: []int64{1000}, Values: []Value{FiniteValue(1000)}})
+
+	got, err := QueryMetric(context.Background(), backend, sloTestOptions(), testLogger())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got != 0 {
+		t.Errorf("burn rate = %v, want 0 without error series", got)
+	}
+
+	// Without traffic nothing is burning.
+	backend.Set("requests")
+	if got, err := QueryMetric(context.Background(), backend, sloTestOptions(), testLogger()); err != nil || got != 0 {
+		t.Errorf("burn rate without total series = %v, %v, want 0", got, err)
+	}
+
+	// Other failures of the total query still fail the call.
+	backend.SetError("requests", &APIError{StatusCode: 503, Kind: APIErrorUnavailable})
+	if _, err := QueryMetric(context.Background(), backend, sloTestOptions(), testLogger()); err == nil {
+		t.Error("expected the total query error")
+	}
+}
+
+func TestQueryMetricBurnRateIgnoresFallback(t *testing.T) {
+	backend := NewMemoryBackend()
+	backend.Set("requests", RawSeries{Timestamps: []int64{1000}, Values: []Value{FiniteValue(1000)}})
+	backend.Set("fallback", RawSeries{Timestamps: []int64{1000}, Values: []Value{FiniteValue(500)}})
+
+	options := sloTestOptions()
+	options.FallbackQuery = "fallback"
+	got, err := QueryMetric(context.Background(), backend, options, testLogger())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got != 0 {
+		t.Errorf("burn rate = %v, want 0: the fallback query must not stand in for the error query", got)
+	}
+	for _, req := range backend.Requests() {
+		if req.Query == "fallback" {
+			t.Fatalf("fallback query was read in SLO mode")
+		}
+	}
+}
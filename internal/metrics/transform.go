@@ -0,0 +1,139 @@
+package metrics
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+type UnitConversion string
+
+const (
+	UnitConversionNone              UnitConversion = ""
+	UnitConversionBytesToKiB        UnitConversion = "bytesToKiB"
+	UnitConversionBytesToMiB        UnitConversion = "bytesToMiB"
+	UnitConversionBytesToGiB        UnitConversion = "bytesToGiB"
+	UnitConversionMillisToSeconds   UnitConversion = "msToS"
+	UnitConversionPercentToFraction UnitConversion = "percentToFraction"
+)
+
+// ValueTransform is applied to the final aggregated value in the order:
+// unit conversion, multiplier, offset, clamping.
+type ValueTransform struct {
+	Unit       UnitConversion
+	Multiplier *float64
+	Offset     float64
+	MinValue   *float64
+	MaxValue   *float64
+}
+
+func (t ValueTransform) IsIdentity() bool {
+	return t.Unit == UnitConversionNone && t.Multiplier == nil && t.Offset == 0 &&
+		t.MinValue == nil && t.MaxValue == nil
+}
+
+func (t ValueTransform) Apply(value float64) float64 {
+	switch t.Unit {
+	case UnitConversionBytesToKiB:
+		value /= 1024
+	case UnitConversionBytesToMiB:
+		value /= 1024 * 1024
+	case UnitConversionBytesToGiB:
+		value /= 1024 * 1024 * 1024
+	case UnitConversionMillisToSeconds:
+		value /= 1000
+	case UnitConversionPercentToFraction:
+		value /= 100
+	}
+
+	if t.Multiplier != nil {
+		value *= *t.Multiplier
+	}
+	value += t.Offset
+
+	if t.MinValue != nil && value < *t.MinValue {
+		value = *t.MinValue
+	}
+	if t.MaxValue != nil && value > *t.MaxValue {
+		value = *t.MaxValue
+	}
+
+	return value
+}
+
+func ParseValueTransform(metadata map[string]string) (ValueTransform, error) {
+	var transform ValueTransform
+
+	unit, err := parseUnitConversion(metadata["unitConversion"])
+	if err != nil {
+		return transform, err
+	}
+	transform.Unit = unit
+
+	if s := metadata["valueMultiplier"]; s != "" {
+		v, err := parseFiniteFloat("valueMultiplier", s)
+		if err != nil {
+			return transform, err
+		}
+		transform.Multiplier = &v
+	}
+
+	if s := metadata["valueOffset"]; s != "" {
+		v, err := parseFiniteFloat("valueOffset", s)
+		if err != nil {
+			return transform, err
+		}
+		transform.Offset = v
+	}
+
+	if s := metadata["minValue"]; s != "" {
+		v, err := parseFiniteFloat("minValue", s)
+		if err != nil {
+			return transform, err
+		}
+		transform.MinValue = &v
+	}
+
+	if s := metadata["maxValue"]; s != "" {
+		v, err := parseFiniteFloat("maxValue", s)
+		if err != nil {
+			return transform, err
+		}
+		transform.MaxValue = &v
+	}
+
+	if transform.MinValue != nil && transform.MaxValue != nil && *transform.MinValue > *transform.MaxValue {
+		return transform, fmt.Errorf("minValue (%g) must not be greater than maxValue (%g)",
+			*transform.MinValue, *transform.MaxValue)
+	}
+
+	return transform, nil
+}
+
+func parseUnitConversion(s string) (UnitConversion, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "", "none":
+		return UnitConversionNone, nil
+	case "bytestokib":
+		return UnitConversionBytesToKiB, nil
+	case "bytestomib":
+		return UnitConversionBytesToMiB, nil
+	case "bytestogib":
+		return UnitConversionBytesToGiB, nil
+	case "mstos", "millisecondstoseconds":
+		return UnitConversionMillisToSeconds, nil
+	case "percenttofraction":
+		return UnitConversionPercentToFraction, nil
+	default:
+		return UnitConversionNone, fmt.Errorf("unsupported unitConversion: %q", s)
+	}
+}
+
+func parseFiniteFloat(name, s string) (float64, error) {
+	v, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	if err != nil || math.IsNaN(v) || math.IsInf(v, 0) {
+		return 0, fmt.Errorf("%s must be a finite number: %q", name, s)
+	}
+	return v, nil
+}
@@ -0,0 +1,49 @@
+package metrics
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestValueTransform(t *testing.T) {
+	tests := []struct {
+		name     string
+		metadata map[string]string
+		value    float64
+		want     float64
+		wantErr  string
+	}{
+		{name: "identity", metadata: map[string]string{}, value: 42, want: 42},
+		{name: "bytes to MiB", metadata: map[string]string{"unitConversion": "bytesToMiB"}, value: 3 * 1024 * 1024, want: 3},
+		{name: "ms to s", metadata: map[string]string{"unitConversion": "msToS"}, value: 1500, want: 1.5},
+		{name: "percent to fraction", metadata: map[string]string{"unitConversion": "percentToFraction"}, value: 75, want: 0.75},
+		{name: "multiplier and offset", metadata: map[string]string{"valueMultiplier": "2", "valueOffset": "-1"}, value: 10, want: 19},
+		{name: "zero multiplier", metadata: map[string]string{"valueMultiplier": "0"}, value: 10, want: 0},
+		{name: "conversion before multiplier", metadata: map[string]string{"unitConversion": "msToS", "valueMultiplier": "10"}, value: 500, want: 5},
+		{name: "clamp min", metadata: map[string]string{"minValue": "5"}, value: 1, want: 5},
+		{name: "clamp max", metadata: map[string]string{"maxValue": "100"}, value: 250, want: 100},
+		{name: "clamp after offset", metadata: map[string]string{"valueOffset": "50", "maxValue": "60"}, value: 20, want: 60},
+		{name: "invalid unit", metadata: map[string]string{"unitConversion": "furlongs"}, wantErr: "unitConversion"},
+		{name: "invalid multiplier", metadata: map[string]string{"valueMultiplier": "NaN"}, wantErr: "valueMultiplier"},
+		{name: "min above max", metadata: map[string]string{"minValue": "10", "maxValue": "1"}, wantErr: "minValue"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			transform, err := ParseValueTransform(tt.metadata)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("ParseValueTransform() error = %v, want substring %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseValueTransform() error = %v", err)
+			}
+			if got := transform.Apply(tt.value); math.Abs(got-tt.want) > 1e-9 {
+				t.Fatalf("Apply(%v) = %v, want %v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
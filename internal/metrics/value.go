@@ -0,0 +1,156 @@
+package metrics
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+type ValueKind int
+
+const (
+	ValueFinite ValueKind = iota
+	ValueNaN
+	ValuePosInf
+	ValueNegInf
+	ValueMissing
+)
+
+func (k ValueKind) String() string {
+	switch k {
+	case ValueFinite:
+		return "finite"
+	case ValueNaN:
+		return "NaN"
+	case ValuePosInf:
+		return "+Inf"
+	case ValueNegInf:
+		return "-Inf"
+	case ValueMissing:
+		return "missing"
+	default:
+		return fmt.Sprintf("ValueKind(%d)", int(k))
+	}
+}
+
+// Value is a single Monitoring data point. Monitoring encodes non-finite
+// doubles as strings and gaps as null, so Float is only meaningful for
+// ValueFinite.
+type Value struct {
+	Kind  ValueKind
+	Float float64
+}
+
+func FiniteValue(f float64) Value {
+	return Value{Kind: ValueFinite, Float: f}
+}
+
+func valueFromFloat(f float64) Value {
+	switch {
+	case math.IsNaN(f):
+		return Value{Kind: ValueNaN}
+	case math.IsInf(f, 1):
+		return Value{Kind: ValuePosInf}
+	case math.IsInf(f, -1):
+		return Value{Kind: ValueNegInf}
+	default:
+		return FiniteValue(f)
+	}
+}
+
+func (v *Value) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+
+	if bytes.Equal(data, []byte("null")) {
+		*v = Value{Kind: ValueMissing}
+		return nil
+	}
+
+	if len(data) > 0 && data[0] == '"' {
+		var s string
+		if err := json.Unmarshal(data, &s); err != nil {
+			return err
+		}
+		return v.parseString(s)
+	}
+
+	f, err := strconv.ParseFloat(string(data), 64)
+	if err != nil {
+		return fmt.Errorf("invalid metric value %s", data)
+	}
+	*v = valueFromFloat(f)
+	return nil
+}
+
+// ParseValue reads a value written as a number, NaN, Infinity or -Infinity;
+// an empty string is a missing value.
+func ParseValue(s string) (Value, error) {
+	var v Value
+	err := v.parseString(s)
+	return v, err
+}
+
+func (v *Value) parseString(s string) error {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "nan", "-nan", "+nan":
+		*v = Value{Kind: ValueNaN}
+		return nil
+	case "infinity", "+infinity", "inf", "+inf":
+		*v = Value{Kind: ValuePosInf}
+		return nil
+	case "-infinity", "-inf":
+		*v = Value{Kind: ValueNegInf}
+		return nil
+	case "", "null":
+		*v = Value{Kind: ValueMissing}
+		return nil
+	}
+
+	f, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	if err != nil {
+		return fmt.Errorf("invalid metric value %q", s)
+	}
+	*v = valueFromFloat(f)
+	return nil
+}
+
+func (v Value) MarshalJSON() ([]byte, error) {
+	switch v.Kind {
+	case ValueFinite:
+		return json.Marshal(v.Float)
+	case ValueNaN:
+		return []byte(`"NaN"`), nil
+	case ValuePosInf:
+		return []byte(`"Infinity"`), nil
+	case ValueNegInf:
+		return []byte(`"-Infinity"`), nil
+	default:
+		return []byte("null"), nil
+	}
+}
+
+func (v Value) String() string {
+	if v.Kind == ValueFinite {
+		return strconv.FormatFloat(v.Float, 'g', -1, 64)
+	}
+	return v.Kind.String()
+}
+
+type ValueCounts struct {
+	Total   int
+	Finite  int
+	NaN     int
+	Inf     int
+	Missing int
+}
+
+func (c *ValueCounts) Add(other ValueCounts) {
+	c.Total += other.Total
+	c.Finite += other.Finite
+	c.NaN += other.NaN
+	c.Inf += other.Inf
+	c.Missing += other.Missing
+}
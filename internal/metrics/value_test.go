@@ -0,0 +1,86 @@
+package metrics
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestValueUnmarshalJSON(t *testing.T) {
+	var ts MetricTimeseries
+	body := `{"timestamps":[1,2,3,4,5,6,7,8],"doubleValues":[1.5,"NaN",null,"Infinity","-Infinity","2.5",3,"inf"]}`
+	if err := json.Unmarshal([]byte(body), &ts); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+
+	want := []Value{
+		FiniteValue(1.5),
+		{Kind: ValueNaN},
+		{Kind: ValueMissing},
+		{Kind: ValuePosInf},
+		{Kind: ValueNegInf},
+		FiniteValue(2.5),
+		FiniteValue(3),
+		{Kind: ValuePosInf},
+	}
+	if len(ts.DoubleValues) != len(want) {
+		t.Fatalf("decoded %d values, want %d", len(ts.DoubleValues), len(want))
+	}
+	for i := range want {
+		if ts.DoubleValues[i] != want[i] {
+			t.Fatalf("value %d = %v, want %v", i, ts.DoubleValues[i], want[i])
+		}
+	}
+
+	if err := json.Unmarshal([]byte(`{"doubleValues":["bogus"]}`), &ts); err == nil {
+		t.Fatalf("Unmarshal() of unknown string succeeded, want error")
+	}
+}
+
+func TestValueMarshalJSONRoundTrip(t *testing.T) {
+	values := []Value{FiniteValue(4), {Kind: ValueNaN}, {Kind: ValuePosInf}, {Kind: ValueNegInf}, {Kind: ValueMissing}}
+	data, err := json.Marshal(values)
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	if string(data) != `[4,"NaN","Infinity","-Infinity",null]` {
+		t.Fatalf("Marshal() = %s", data)
+	}
+
+	var decoded []Value
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	for i := range values {
+		if decoded[i] != values[i] {
+			t.Fatalf("round trip value %d = %v, want %v", i, decoded[i], values[i])
+		}
+	}
+}
+
+func TestExtractValidPointsByKind(t *testing.T) {
+	timestamps := []int64{1000, 2000, 3000, 4000, 5000}
+	values := []Value{FiniteValue(10), {Kind: ValueNaN}, {Kind: ValuePosInf}, {Kind: ValueMissing}, FiniteValue(20)}
+
+	got, counts := ExtractValidPoints(timestamps, values, NaNStrategyLastValid, NaNStrategySkip, 0)
+	if vals := pointValues(got); len(vals) != 4 || vals[0] != 10 || vals[1] != 10 || vals[2] != 10 || vals[3] != 20 {
+		t.Fatalf("ExtractValidPoints() = %v", got)
+	}
+	want := ValueCounts{Total: 5, Finite: 2, NaN: 1, Inf: 1, Missing: 1}
+	if counts != want {
+		t.Fatalf("counts = %+v, want %+v", counts, want)
+	}
+
+	got, _ = ExtractValidPoints(timestamps, values, NaNStrategySkip, NaNStrategyZero, 0)
+	if len(got) != 3 || got[1] != (Point{Timestamp: 3000, Value: 0}) {
+		t.Fatalf("ExtractValidPoints() with inf=zero = %v", got)
+	}
+}
+
+func TestParseInfStrategy(t *testing.T) {
+	if got := ParseInfStrategy("", NaNStrategyZero); got != NaNStrategyZero {
+		t.Fatalf("ParseInfStrategy(empty) = %q, want nanStrategy fallback", got)
+	}
+	if got := ParseInfStrategy("skip", NaNStrategyZero); got != NaNStrategySkip {
+		t.Fatalf("ParseInfStrategy(skip) = %q", got)
+	}
+}
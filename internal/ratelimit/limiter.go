@@ -0,0 +1,238 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type waiter struct {
+	key     string
+	ready   chan struct{}
+	granted bool
+}
+
+// Limiter combines a token bucket with a limit on concurrent calls. Waiting
+// callers are grouped by key and served round-robin, so a caller issuing many
+// requests cannot starve the others.
+type Limiter struct {
+	mu          sync.Mutex
+	rate        float64 // tokens per second, 0 for no rate limit
+	burst       float64
+	maxInFlight int // 0 for no concurrency limit
+
+	tokens   float64
+	last     time.Time
+	inFlight int
+
+	queues map[string][]*waiter
+	order  []string // keys with waiters, next to be served first
+	timer  *time.Timer
+}
+
+func New(rate float64, burst, maxInFlight int) *Limiter {
+	if burst < 1 {
+		burst = 1
+	}
+	return &Limiter{
+		rate:        rate,
+		burst:       float64(burst),
+		maxInFlight: maxInFlight,
+		tokens:      float64(burst),
+		last:        time.Now(),
+		queues:      map[string][]*waiter{},
+	}
+}
+
+func (l *Limiter) Enabled() bool {
+	return l.rate > 0 || l.maxInFlight > 0
+}
+
+// Acquire blocks until the caller identified by key may proceed or ctx is
+// done. The returned release function must be called when the call finishes.
+func (l *Limiter) Acquire(ctx context.Context, key string) (func(), error) {
+	if !l.Enabled() {
+		return func() {}, nil
+	}
+
+	w := &waiter{key: key, ready: make(chan struct{})}
+	l.mu.Lock()
+	if len(l.queues[key]) == 0 {
+		l.order = append(l.order, key)
+	}
+	l.queues[key] = append(l.queues[key], w)
+	l.dispatchLocked()
+	l.mu.Unlock()
+
+	select {
+	case <-w.ready:
+		return l.releaseFunc(), nil
+	case <-ctx.Done():
+		l.mu.Lock()
+		granted := w.granted
+		if !granted {
+			l.removeLocked(w)
+		}
+		l.mu.Unlock()
+		if granted {
+			l.releaseFunc()()
+		}
+		return nil, ctx.Err()
+	}
+}
+
+func (l *Limiter) releaseFunc() func() {
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			l.mu.Lock()
+			l.inFlight--
+			l.dispatchLocked()
+			l.mu.Unlock()
+		})
+	}
+}
+
+func (l *Limiter) dispatchLocked() {
+	for len(l.order) > 0 {
+		if l.maxInFlight > 0 && l.inFlight >= l.maxInFlight {
+			return
+		}
+		if l.rate > 0 {
+			l.refillLocked()
+			if l.tokens < 1 {
+				l.scheduleLocked(time.Duration((1 - l.tokens) / l.rate * float64(time.Second)))
+				return
+			}
+			l.tokens--
+		}
+
+		key := l.order[0]
+		l.order = l.order[1:]
+		queue := l.queues[key]
+		w := queue[0]
+		if len(queue) > 1 {
+			l.queues[key] = queue[1:]
+			l.order = append(l.order, key)
+		} else {
+			delete(l.queues, key)
+		}
+
+		w.granted = true
+		l.inFlight++
+		close(w.ready)
+	}
+}
+
+func (l *Limiter) refillLocked() {
+	now := time.Now()
+	l.tokens += now.Sub(l.last).Seconds() * l.rate
+	if l.tokens > l.burst {
+		l.tokens = l.burst
+	}
+	l.last = now
+}
+
+func (l *Limiter) scheduleLocked(wait time.Duration) {
+	if l.timer != nil {
+		return
+	}
+	l.timer = time.AfterFunc(wait, func() {
+		l.mu.Lock()
+		l.timer = nil
+		l.dispatchLocked()
+		l.mu.Unlock()
+	})
+}
+
+func (l *Limiter) removeLocked(w *waiter) {
+	queue := l.queues[w.key]
+	for i, q := range queue {
+		if q == w {
+			queue = append(queue[:i:i], queue[i+1:]...)
+			break
+		}
+	}
+	if len(queue) > 0 {
+		l.queues[w.key] = queue
+		return
+	}
+
+	delete(l.queues, w.key)
+	for i, key := range l.order {
+		if key == w.key {
+			l.order = append(l.order[:i:i], l.order[i+1:]...)
+			break
+		}
+	}
+}
+
+// idle reports whether the limiter is in the state of a new one: nothing in
+// flight or waiting, and a full bucket.
+func (l *Limiter) idle() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.inFlight > 0 || len(l.order) > 0 {
+		return false
+	}
+	if l.rate > 0 {
+		l.refillLocked()
+		return l.tokens >= l.burst
+	}
+	return true
+}
+
+// idleTimeout is how long a limiter may go unused before a Set drops it.
+const idleTimeout = 10 * time.Minute
+
+// Set holds one limiter per key, created on first use with shared settings.
+// Limiters unused for idleTimeout are dropped once they are idle, so keys
+// that are no longer used do not accumulate.
+type Set struct {
+	mu          sync.Mutex
+	rate        float64
+	burst       int
+	maxInFlight int
+	now         func() time.Time
+
+	limiters  map[string]*setEntry
+	lastSweep time.Time
+}
+
+type setEntry struct {
+	limiter  *Limiter
+	lastUsed time.Time
+}
+
+func NewSet(rate float64, burst, maxInFlight int) *Set {
+	return &Set{rate: rate, burst: burst, maxInFlight: maxInFlight, now: time.Now, limiters: map[string]*setEntry{}}
+}
+
+func (s *Set) Get(key string) *Limiter {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	s.sweepLocked(now)
+
+	e, ok := s.limiters[key]
+	if !ok {
+		e = &setEntry{limiter: New(s.rate, s.burst, s.maxInFlight)}
+		s.limiters[key] = e
+	}
+	e.lastUsed = now
+	return e.limiter
+}
+
+func (s *Set) sweepLocked(now time.Time) {
+	if now.Sub(s.lastSweep) < idleTimeout {
+		return
+	}
+	s.lastSweep = now
+	for key, e := range s.limiters {
+		if now.Sub(e.lastUsed) >= idleTimeout && e.limiter.idle() {
+			delete(s.limiters, key)
+		}
+	}
+}
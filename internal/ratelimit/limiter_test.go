@@ -0,0 +1,132 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestLimiterMaxInFlight(t *testing.T) {
+	l := New(0, 0, 1)
+
+	release, err := l.Acquire(context.Background(), "a")
+	if err != nil {
+		t.Fatalf("Acquire() = %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := l.Acquire(ctx, "b"); err != context.DeadlineExceeded {
+		t.Fatalf("Acquire() while full = %v, want deadline exceeded", err)
+	}
+
+	release()
+	release() // releasing twice must not free a second slot
+	if _, err := l.Acquire(context.Background(), "b"); err != nil {
+		t.Fatalf("Acquire() after release = %v", err)
+	}
+	ctx2, cancel2 := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel2()
+	if _, err := l.Acquire(ctx2, "c"); err == nil {
+		t.Fatal("double release freed an extra slot")
+	}
+}
+
+func TestLimiterRate(t *testing.T) {
+	l := New(50, 1, 0)
+
+	start := time.Now()
+	for i := 0; i < 4; i++ {
+		release, err := l.Acquire(context.Background(), "a")
+		if err != nil {
+			t.Fatalf("Acquire() = %v", err)
+		}
+		release()
+	}
+	// One token is available immediately, the other three take 20ms each.
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+		t.Fatalf("4 acquisitions at 50/s took %v, want at least 50ms", elapsed)
+	}
+}
+
+func TestLimiterServesKeysRoundRobin(t *testing.T) {
+	l := New(0, 0, 1)
+	hold, _ := l.Acquire(context.Background(), "busy")
+
+	var (
+		mu    sync.Mutex
+		order []string
+		wg    sync.WaitGroup
+	)
+	queued := 0
+	enqueue := func(key string) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			release, err := l.Acquire(context.Background(), key)
+			if err != nil {
+				t.Errorf("Acquire(%s) = %v", key, err)
+				return
+			}
+			mu.Lock()
+			order = append(order, key)
+			mu.Unlock()
+			release()
+		}()
+		// Wait until the waiter is queued so the arrival order is fixed.
+		queued++
+		for waiters(l) < queued {
+			time.Sleep(time.Millisecond)
+		}
+	}
+
+	// A noisy caller queues three requests before a quiet one queues its own.
+	enqueue("noisy")
+	enqueue("noisy")
+	enqueue("noisy")
+	enqueue("quiet")
+	hold()
+	wg.Wait()
+
+	if len(order) != 4 || order[1] != "quiet" {
+		t.Fatalf("service order = %v, want quiet served second", order)
+	}
+}
+
+func waiters(l *Limiter) int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	n := 0
+	for _, q := range l.queues {
+		n += len(q)
+	}
+	return n
+}
+
+func TestSetDropsIdleLimiters(t *testing.T) {
+	now := time.Date(2026, time.March, 1, 12, 0, 0, 0, time.UTC)
+	s := NewSet(0, 0, 1)
+	s.now = func() time.Time { return now }
+
+	s.Get("idle")
+	busy := s.Get("busy")
+	release, err := busy.Acquire(context.Background(), "q")
+	if err != nil {
+		t.Fatalf("Acquire() = %v", err)
+	}
+
+	now = now.Add(idleTimeout)
+	s.Get("active")
+	if len(s.limiters) != 2 || s.limiters["busy"] == nil || s.limiters["idle"] != nil {
+		t.Fatalf("limiters after idle timeout = %v, want idle dropped and busy kept", s.limiters)
+	}
+
+	// A limiter with a call in flight is kept until the call is released.
+	release()
+	now = now.Add(idleTimeout)
+	s.Get("active")
+	if len(s.limiters) != 1 || s.limiters["active"] == nil {
+		t.Fatalf("limiters after release = %v, want only the active limiter", s.limiters)
+	}
+}
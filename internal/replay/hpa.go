@@ -0,0 +1,136 @@
+package replay
+
+import (
+	"math"
+	"time"
+)
+
+const (
+	defaultTolerance              = 0.1
+	defaultScaleDownStabilization = 5 * time.Minute
+	defaultCooldownPeriod         = 5 * time.Minute
+)
+
+// HPAConfig mirrors the ScaledObject and HPA settings that decide replica
+// counts. Zero durations and tolerance take the Kubernetes and KEDA defaults;
+// a negative value disables the setting.
+type HPAConfig struct {
+	MinReplicas            int64 // minReplicaCount; 0 allows KEDA to scale to zero
+	MaxReplicas            int64
+	InitialReplicas        int64 // Defaults to MinReplicas, or 1 when that is 0
+	Tolerance              float64
+	ScaleUpStabilization   time.Duration
+	ScaleDownStabilization time.Duration
+	CooldownPeriod         time.Duration
+}
+
+type recommendation struct {
+	at       time.Time
+	replicas int64
+}
+
+// HPA simulates the replica count of a Deployment scaled by an HPA on a
+// single AverageValue external metric, with KEDA handling activation and
+// scaling to zero. Scaling policies (pods or percent per period) are not
+// modeled.
+type HPA struct {
+	config          HPAConfig
+	replicas        int64
+	lastActive      time.Time
+	recommendations []recommendation
+}
+
+func NewHPA(cfg HPAConfig) *HPA {
+	if cfg.Tolerance == 0 {
+		cfg.Tolerance = defaultTolerance
+	}
+	if cfg.ScaleDownStabilization == 0 {
+		cfg.ScaleDownStabilization = defaultScaleDownStabilization
+	}
+	if cfg.CooldownPeriod == 0 {
+		cfg.CooldownPeriod = defaultCooldownPeriod
+	}
+
+	replicas := cfg.InitialReplicas
+	if replicas == 0 {
+		replicas = cfg.minHPAReplicas()
+	}
+	return &HPA{config: cfg, replicas: replicas}
+}
+
+func (h *HPA) Replicas() int64 {
+	return h.replicas
+}
+
+// minHPAReplicas is the HPA's own lower bound, which cannot be 0.
+func (c HPAConfig) minHPAReplicas() int64 {
+	if c.MinReplicas < 1 {
+		return 1
+	}
+	return c.MinReplicas
+}
+
+// Step applies one polling cycle at now and returns the new replica count.
+func (h *HPA) Step(now time.Time, value, target float64, active bool) int64 {
+	if active {
+		h.lastActive = now
+	}
+
+	if h.replicas == 0 {
+		if active {
+			h.replicas = h.config.minHPAReplicas()
+		}
+		return h.replicas
+	}
+	if h.config.MinReplicas == 0 && !active && now.Sub(h.lastActive) >= h.config.CooldownPeriod {
+		h.replicas = 0
+		h.recommendations = nil
+		return 0
+	}
+
+	desired := h.replicas
+	ratio := value / (target * float64(h.replicas))
+	if math.Abs(ratio-1) > h.config.Tolerance {
+		desired = int64(math.Ceil(value / target))
+	}
+	if min := h.config.minHPAReplicas(); desired < min {
+		desired = min
+	}
+	if h.config.MaxReplicas > 0 && desired > h.config.MaxReplicas {
+		desired = h.config.MaxReplicas
+	}
+
+	h.recommendations = append(h.recommendations, recommendation{at: now, replicas: desired})
+	h.replicas = h.stabilize(now, desired)
+	return h.replicas
+}
+
+// stabilize only scales up to the lowest recommendation of the scale-up
+// window and down to the highest of the scale-down window, like the HPA's
+// stabilizationWindowSeconds.
+func (h *HPA) stabilize(now time.Time, desired int64) int64 {
+	up, down := desired, desired
+	kept := h.recommendations[:0]
+	for _, r := range h.recommendations {
+		age := now.Sub(r.at)
+		if age <= h.config.ScaleUpStabilization && r.replicas < up {
+			up = r.replicas
+		}
+		if age <= h.config.ScaleDownStabilization && r.replicas > down {
+			down = r.replicas
+		}
+		if age <= h.config.ScaleUpStabilization || age <= h.config.ScaleDownStabilization {
+			kept = append(kept, r)
+		}
+	}
+	h.recommendations = kept
+
+	result := h.replicas
+	if result < up {
+		result = up
+	}
+	if result > down {
+		result = down
+	}
+	return result
+}
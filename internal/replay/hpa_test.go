@@ -0,0 +1,52 @@
+package replay
+
+import (
+	"testing"
+	"time"
+)
+
+func TestHPAStep(t *testing.T) {
+	start := time.Date(2026, time.May, 4, 10, 0, 0, 0, time.UTC)
+	hpa := NewHPA(HPAConfig{MinReplicas: 1, MaxReplicas: 8, InitialReplicas: 2, ScaleDownStabilization: 2 * time.Minute})
+
+	steps := []struct {
+		value float64
+		want  int64
+	}{
+		{value: 210, want: 2}, // within the 10% tolerance of 2 x 100
+		{value: 450, want: 5},
+		{value: 1200, want: 8}, // capped at maxReplicas
+		{value: 100, want: 8},  // scale-down held by the stabilization window
+		{value: 100, want: 8},
+		{value: 100, want: 1}, // 8 left the window
+	}
+	for i, step := range steps {
+		got := hpa.Step(start.Add(time.Duration(i)*time.Minute), step.value, 100, true)
+		if got != step.want {
+			t.Fatalf("step %d (value %v): replicas = %d, want %d", i, step.value, got, step.want)
+		}
+	}
+}
+
+func TestHPAScalesToZeroAfterCooldown(t *testing.T) {
+	start := time.Date(2026, time.May, 4, 10, 0, 0, 0, time.UTC)
+	hpa := NewHPA(HPAConfig{MinReplicas: 0, MaxReplicas: 4, CooldownPeriod: 2 * time.Minute, ScaleDownStabilization: -1})
+
+	steps := []struct {
+		value  float64
+		active bool
+		want   int64
+	}{
+		{value: 50, active: true, want: 1},
+		{value: 0, active: false, want: 1},
+		{value: 0, active: false, want: 0},  // cooldown elapsed
+		{value: 300, active: true, want: 1}, // KEDA activates to one replica first
+		{value: 300, active: true, want: 3},
+	}
+	for i, step := range steps {
+		got := hpa.Step(start.Add(time.Duration(i)*time.Minute), step.value, 100, step.active)
+		if got != step.want {
+			t.Fatalf("step %d: replicas = %d, want %d", i, got, step.want)
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package replay
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	protos "keda-external-scaler-yc-monitoring/gen/proto/externalscaler"
+	"keda-external-scaler-yc-monitoring/internal/config"
+	"keda-external-scaler-yc-monitoring/internal/server"
+)
+
+type Config struct {
+	Metadata map[string]string // ScaledObject trigger metadata
+	Interval time.Duration     // KEDA polling interval
+	From     time.Time         // Defaults to the start of the recording
+	To       time.Time         // Defaults to the end of the recording
+	HPA      HPAConfig
+}
+
+// Sample is the state after one polling cycle.
+type Sample struct {
+	Time     time.Time
+	Value    float64
+	Target   float64
+	Active   bool
+	Replicas int64
+	Err      error
+}
+
+// Run replays the recording through the scaler's RPCs at every polling
+// interval, evaluating queries as of the simulated time, and feeds the
+// results to a simulated HPA. A failed GetMetrics keeps the replica count, as
+// the HPA does when the metric is unavailable.
+func Run(ctx context.Context, backend *Backend, cfg Config) ([]Sample, error) {
+	if cfg.Interval <= 0 {
+		return nil, fmt.Errorf("polling interval must be positive")
+	}
+
+	metadata := map[string]string{"logLevel": "none"}
+	for k, v := range cfg.Metadata {
+		metadata[k] = v
+	}
+
+	from, to := cfg.From, cfg.To
+	if from.IsZero() || to.IsZero() {
+		first, last, ok := backend.Range()
+		if !ok {
+			return nil, fmt.Errorf("the recording has no points")
+		}
+		// The query window ends timeWindowOffset before the evaluation time,
+		// so the recording is fully covered when the replay is shifted by it.
+		offset := 30 * time.Second
+		if d, err := time.ParseDuration(metadata["timeWindowOffset"]); err == nil {
+			offset = d
+		}
+		if from.IsZero() {
+			from = first.Add(offset)
+		}
+		if to.IsZero() {
+			to = last.Add(offset)
+		}
+	}
+	if to.Before(from) {
+		return nil, fmt.Errorf("replay range ends at %s before it starts at %s", to.Format(time.RFC3339), from.Format(time.RFC3339))
+	}
+
+	var now time.Time
+	scaler := server.NewExternalScalerServerWithBackend(backend, &config.Config{})
+	scaler.SetClock(func() time.Time { return now })
+
+	ref := &protos.ScaledObjectRef{Name: "replay", Namespace: "replay", ScalerMetadata: metadata}
+	spec, err := scaler.GetMetricSpec(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+	target := spec.MetricSpecs[0].TargetSizeFloat
+
+	hpa := NewHPA(cfg.HPA)
+	var samples []Sample
+	for now = from; !now.After(to); now = now.Add(cfg.Interval) {
+		if err := ctx.Err(); err != nil {
+			return samples, err
+		}
+
+		sample := Sample{Time: now, Target: target, Replicas: hpa.Replicas()}
+		active, err := scaler.IsActive(ctx, ref)
+		if err != nil {
+			return samples, err
+		}
+		sample.Active = active.Result
+
+		resp, err := scaler.GetMetrics(ctx, &protos.GetMetricsRequest{ScaledObjectRef: ref, MetricName: "yandex_monitoring_metric"})
+		if err != nil {
+			sample.Err = err
+		} else {
+			sample.Value = resp.MetricValues[0].MetricValueFloat
+			sample.Replicas = hpa.Step(now, sample.Value, target, sample.Active)
+		}
+		samples = append(samples, sample)
+	}
+	return samples, nil
+}
+
+func WriteTable(w io.Writer, samples []Sample) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "TIME\tVALUE\tTARGET\tACTIVE\tREPLICAS\tERROR")
+	for _, s := range samples {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%t\t%d\t%s\n",
+			s.Time.Format(time.RFC3339), formatValue(s), formatFloat(s.Target), s.Active, s.Replicas, errorText(s.Err))
+	}
+	return tw.Flush()
+}
+
+func WriteCSV(w io.Writer, samples []Sample) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"time", "value", "target", "active", "replicas", "error"})
+	for _, s := range samples {
+		cw.Write([]string{
+			s.Time.Format(time.RFC3339),
+			formatValue(s),
+			formatFloat(s.Target),
+			strconv.FormatBool(s.Active),
+			strconv.FormatInt(s.Replicas, 10),
+			errorText(s.Err),
+		})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func formatValue(s Sample) string {
+	if s.Err != nil {
+		return ""
+	}
+	return formatFloat(s.Value)
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+func errorText(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
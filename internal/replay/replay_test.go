@@ -0,0 +1,84 @@
+package replay
+
+import (
+	"bytes"
+	"context"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"keda-external-scaler-yc-monitoring/internal/metrics"
+)
+
+func TestParseCSV(t *testing.T) {
+	input := `timestamp,value,shard
+2026-05-04T10:01:00Z,20,a
+2026-05-04T10:00:00Z,10,a
+1777888800000,NaN,b
+2026-05-04T10:01:00Z,,b
+`
+	series, err := ParseCSV(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("ParseCSV() error = %v", err)
+	}
+	if len(series) != 2 {
+		t.Fatalf("ParseCSV() returned %d series, want 2", len(series))
+	}
+	a, b := series[0], series[1]
+	if a.Labels["shard"] != "a" || a.Timestamps[0] != 1777888800000 || a.Values[0] != metrics.FiniteValue(10) {
+		t.Errorf("series a = %+v, want sorted points starting at 10", a)
+	}
+	if b.Values[0].Kind != metrics.ValueNaN || b.Values[1].Kind != metrics.ValueMissing {
+		t.Errorf("series b values = %v, want NaN and missing", b.Values)
+	}
+
+	if _, err := ParseCSV(strings.NewReader("time,count\n1,2\n")); err == nil {
+		t.Error("ParseCSV() without a value column succeeded")
+	}
+}
+
+func TestRun(t *testing.T) {
+	start := time.Date(2026, time.May, 4, 10, 0, 0, 0, time.UTC)
+	recording := `{"metrics":[{"name":"rps","timeseries":{"timestamps":[` +
+		ms(start) + `,` + ms(start.Add(time.Minute)) + `,` + ms(start.Add(2*time.Minute)) + `,` + ms(start.Add(3*time.Minute)) +
+		`],"doubleValues":[100,"NaN",450,380]}}]}`
+	series, err := ParseJSON(strings.NewReader(recording))
+	if err != nil {
+		t.Fatalf("ParseJSON() error = %v", err)
+	}
+
+	samples, err := Run(context.Background(), NewBackend(series), Config{
+		Metadata: map[string]string{
+			"query":             "rps",
+			"folderId":          "folder",
+			"targetValue":       "100",
+			"timeWindow":        "1m",
+			"timeWindowOffset":  "0s",
+			"aggregationMethod": "last",
+		},
+		Interval: time.Minute,
+		HPA:      HPAConfig{MinReplicas: 1, MaxReplicas: 10},
+	})
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	var out bytes.Buffer
+	if err := WriteCSV(&out, samples); err != nil {
+		t.Fatal(err)
+	}
+	want := `time,value,target,active,replicas,error
+2026-05-04T10:00:00Z,100,100,true,1,
+2026-05-04T10:01:00Z,100,100,true,1,
+2026-05-04T10:02:00Z,450,100,true,5,
+2026-05-04T10:03:00Z,380,100,true,5,
+`
+	if out.String() != want {
+		t.Fatalf("replay output:\n%s\nwant:\n%s", out.String(), want)
+	}
+}
+
+func ms(t time.Time) string {
+	return strconv.FormatInt(t.UnixMilli(), 10)
+}
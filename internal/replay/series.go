@@ -0,0 +1,187 @@
+package replay
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"keda-external-scaler-yc-monitoring/internal/logger"
+	"keda-external-scaler-yc-monitoring/internal/metrics"
+)
+
+// LoadSeries reads a recording: a Monitoring data/read response as JSON, or a
+// CSV file when the name ends in .csv.
+func LoadSeries(path string) ([]metrics.RawSeries, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open recording: %w", err)
+	}
+	defer f.Close()
+
+	var series []metrics.RawSeries
+	if strings.EqualFold(filepath.Ext(path), ".csv") {
+		series, err = ParseCSV(f)
+	} else {
+		series, err = ParseJSON(f)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return series, nil
+}
+
+func ParseJSON(r io.Reader) ([]metrics.RawSeries, error) {
+	var resp metrics.MetricResponse
+	if err := json.NewDecoder(r).Decode(&resp); err != nil {
+		return nil, fmt.Errorf("invalid Monitoring response: %v", err)
+	}
+
+	series := make([]metrics.RawSeries, 0, len(resp.Metrics))
+	for _, m := range resp.Metrics {
+		series = append(series, metrics.RawSeries{
+			Name:       m.Name,
+			Labels:     m.Labels,
+			Timestamps: m.Timeseries.Timestamps,
+			Values:     m.Timeseries.Values(),
+		})
+	}
+	return series, nil
+}
+
+// ParseCSV reads rows of timestamp and value. Timestamps are RFC 3339 or Unix
+// milliseconds; values are numbers, NaN, Infinity or empty for a gap. Any
+// other column is a label, and rows with the same labels form one series.
+func ParseCSV(r io.Reader) ([]metrics.RawSeries, error) {
+	rows, err := csv.NewReader(r).ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("invalid CSV: %v", err)
+	}
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("empty CSV")
+	}
+
+	header := rows[0]
+	tsCol, valueCol := -1, -1
+	for i, name := range header {
+		switch strings.ToLower(strings.TrimSpace(name)) {
+		case "timestamp", "time":
+			tsCol = i
+		case "value":
+			valueCol = i
+		}
+	}
+	if tsCol < 0 || valueCol < 0 {
+		return nil, fmt.Errorf("CSV header must have timestamp and value columns: %v", header)
+	}
+
+	index := map[string]int{}
+	var series []metrics.RawSeries
+	for line, row := range rows[1:] {
+		ts, err := parseTimestamp(row[tsCol])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", line+2, err)
+		}
+		value, err := metrics.ParseValue(row[valueCol])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", line+2, err)
+		}
+
+		labels := map[string]string{}
+		var key []string
+		for i, name := range header {
+			if i != tsCol && i != valueCol {
+				labels[name] = row[i]
+				key = append(key, name+"="+row[i])
+			}
+		}
+		i, ok := index[strings.Join(key, ",")]
+		if !ok {
+			i = len(series)
+			index[strings.Join(key, ",")] = i
+			series = append(series, metrics.RawSeries{Name: labels["name"], Labels: labels})
+		}
+		series[i].Timestamps = append(series[i].Timestamps, ts)
+		series[i].Values = append(series[i].Values, value)
+	}
+
+	for _, s := range series {
+		sort.Sort(byTimestamp(s))
+	}
+	return series, nil
+}
+
+func parseTimestamp(s string) (int64, error) {
+	s = strings.TrimSpace(s)
+	if ms, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return ms, nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid timestamp %q", s)
+	}
+	return t.UnixMilli(), nil
+}
+
+type byTimestamp metrics.RawSeries
+
+func (s byTimestamp) Len() int           { return len(s.Timestamps) }
+func (s byTimestamp) Less(i, j int) bool { return s.Timestamps[i] < s.Timestamps[j] }
+func (s byTimestamp) Swap(i, j int) {
+	s.Timestamps[i], s.Timestamps[j] = s.Timestamps[j], s.Timestamps[i]
+	s.Values[i], s.Values[j] = s.Values[j], s.Values[i]
+}
+
+// Backend serves a recording as if it were live: every read returns the
+// recorded points inside the requested range, whatever the query.
+type Backend struct {
+	series []metrics.RawSeries
+}
+
+func NewBackend(series []metrics.RawSeries) *Backend {
+	return &Backend{series: series}
+}
+
+func (b *Backend) Read(ctx context.Context, req metrics.ReadRequest, logger *logger.Logger) ([]metrics.RawSeries, error) {
+	from, to := req.From.UnixMilli(), req.To.UnixMilli()
+
+	var result []metrics.RawSeries
+	for _, s := range b.series {
+		clipped := metrics.RawSeries{Name: s.Name, Labels: s.Labels}
+		for i, ts := range s.Timestamps {
+			if ts >= from && ts <= to && i < len(s.Values) {
+				clipped.Timestamps = append(clipped.Timestamps, ts)
+				clipped.Values = append(clipped.Values, s.Values[i])
+			}
+		}
+		if len(clipped.Timestamps) > 0 {
+			result = append(result, clipped)
+		}
+	}
+	return result, nil
+}
+
+// Range returns the first and last recorded timestamps.
+func (b *Backend) Range() (time.Time, time.Time, bool) {
+	var first, last int64
+	found := false
+	for _, s := range b.series {
+		for _, ts := range s.Timestamps {
+			if !found || ts < first {
+				first = ts
+			}
+			if !found || ts > last {
+				last = ts
+			}
+			found = true
+		}
+	}
+	return time.UnixMilli(first).UTC(), time.UnixMilli(last).UTC(), found
+}
@@ -0,0 +1,168 @@
+package schedule
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Cron is a parsed standard five-field cron expression:
+// minute hour day-of-month month day-of-week.
+type Cron struct {
+	minutes    uint64
+	hours      uint64
+	daysOfMon  uint64
+	months     uint64
+	daysOfWeek uint64
+
+	domRestricted bool
+	dowRestricted bool
+}
+
+type cronField struct {
+	name  string
+	min   int
+	max   int
+	names map[string]int
+}
+
+var (
+	minuteField = cronField{name: "minute", min: 0, max: 59}
+	hourField   = cronField{name: "hour", min: 0, max: 23}
+	domField    = cronField{name: "day-of-month", min: 1, max: 31}
+	monthField  = cronField{name: "month", min: 1, max: 12, names: map[string]int{
+		"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+		"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+	}}
+	dowField = cronField{name: "day-of-week", min: 0, max: 7, names: map[string]int{
+		"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+	}}
+)
+
+func ParseCron(expr string) (*Cron, error) {
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("cron expression %q must have 5 fields", expr)
+	}
+
+	var cron Cron
+	var err error
+	if cron.minutes, err = minuteField.parse(fields[0]); err != nil {
+		return nil, err
+	}
+	if cron.hours, err = hourField.parse(fields[1]); err != nil {
+		return nil, err
+	}
+	if cron.daysOfMon, err = domField.parse(fields[2]); err != nil {
+		return nil, err
+	}
+	if cron.months, err = monthField.parse(fields[3]); err != nil {
+		return nil, err
+	}
+	if cron.daysOfWeek, err = dowField.parse(fields[4]); err != nil {
+		return nil, err
+	}
+	if cron.daysOfWeek&(1<<7) != 0 {
+		cron.daysOfWeek |= 1
+	}
+
+	// As in Vixie cron, a field starting with "*" (including "*/2") leaves
+	// the day unrestricted, so it is ANDed rather than ORed with the other.
+	cron.domRestricted = !strings.HasPrefix(fields[2], "*")
+	cron.dowRestricted = !strings.HasPrefix(fields[4], "*")
+
+	return &cron, nil
+}
+
+func (f cronField) parse(s string) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(s, ",") {
+		rangePart, step := part, 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			rangePart = part[:i]
+			n, err := strconv.Atoi(part[i+1:])
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("invalid %s step in %q", f.name, s)
+			}
+			step = n
+		}
+
+		low, high := f.min, f.max
+		if rangePart != "*" {
+			bounds := strings.SplitN(rangePart, "-", 2)
+			var err error
+			if low, err = f.value(bounds[0]); err != nil {
+				return 0, fmt.Errorf("invalid %s in %q", f.name, s)
+			}
+			high = low
+			if len(bounds) == 2 {
+				if high, err = f.value(bounds[1]); err != nil {
+					return 0, fmt.Errorf("invalid %s in %q", f.name, s)
+				}
+			} else if step > 1 {
+				high = f.max
+			}
+			if low > high {
+				return 0, fmt.Errorf("invalid %s range in %q", f.name, s)
+			}
+		}
+
+		for v := low; v <= high; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+	return bits, nil
+}
+
+func (f cronField) value(s string) (int, error) {
+	if v, ok := f.names[strings.ToLower(s)]; ok {
+		return v, nil
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil || v < f.min || v > f.max {
+		return 0, fmt.Errorf("value %q out of range", s)
+	}
+	return v, nil
+}
+
+func (c *Cron) matchesDay(t time.Time) bool {
+	if c.months&(1<<uint(t.Month())) == 0 {
+		return false
+	}
+	dom := c.daysOfMon&(1<<uint(t.Day())) != 0
+	dow := c.daysOfWeek&(1<<uint(t.Weekday())) != 0
+	if c.domRestricted && c.dowRestricted {
+		return dom || dow
+	}
+	return dom && dow
+}
+
+// Previous returns the latest fire time at or before t, searching back no
+// further than limit. The second result is false if none was found.
+func (c *Cron) Previous(t time.Time, limit time.Duration) (time.Time, bool) {
+	earliest := t.Add(-limit)
+	current := t.Truncate(time.Minute)
+	if current.Second() != 0 || current.Nanosecond() != 0 {
+		// Truncate works on absolute time; realign in zones with sub-minute offsets.
+		current = time.Date(current.Year(), current.Month(), current.Day(),
+			current.Hour(), current.Minute(), 0, 0, current.Location())
+	}
+
+	for !current.Before(earliest) {
+		if !c.matchesDay(current) {
+			current = time.Date(current.Year(), current.Month(), current.Day(), 0, 0, 0, 0, current.Location()).Add(-time.Minute)
+			continue
+		}
+		if c.hours&(1<<uint(current.Hour())) == 0 {
+			current = time.Date(current.Year(), current.Month(), current.Day(), current.Hour(), 0, 0, 0, current.Location()).Add(-time.Minute)
+			continue
+		}
+		if c.minutes&(1<<uint(current.Minute())) != 0 {
+			return current, true
+		}
+		current = current.Add(-time.Minute)
+	}
+
+	return time.Time{}, false
+}
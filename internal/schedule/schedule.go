@@ -0,0 +1,185 @@
+package schedule
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	_ "time/tzdata"
+)
+
+const maxWindowDuration = 31 * 24 * time.Hour
+
+// Window is active for Duration after each fire time of Start, evaluated in
+// Location.
+type Window struct {
+	Name        string
+	Start       *Cron
+	Duration    time.Duration
+	Location    *time.Location
+	TargetValue *float64
+	MinValue    *float64
+	ForceActive bool
+}
+
+func (w Window) ActiveAt(now time.Time) bool {
+	local := now.In(w.Location)
+	start, ok := w.Start.Previous(local, w.Duration)
+	return ok && local.Sub(start) < w.Duration
+}
+
+// Override is the combined effect of all windows active at a given time.
+// Overlapping windows resolve to the lowest targetValue and the highest
+// minValue so that they never reduce capacity compared to each other.
+type Override struct {
+	Windows     []string
+	TargetValue *float64
+	MinValue    *float64
+	ForceActive bool
+}
+
+func (o Override) Active() bool {
+	return len(o.Windows) > 0
+}
+
+// Target returns the scheduled target value, or targetValue when no active
+// window sets one.
+func (o Override) Target(targetValue float64) float64 {
+	if o.TargetValue == nil {
+		return targetValue
+	}
+	return *o.TargetValue
+}
+
+type Policy struct {
+	Windows []Window
+}
+
+func (p Policy) Evaluate(now time.Time) Override {
+	var override Override
+	for _, w := range p.Windows {
+		if !w.ActiveAt(now) {
+			continue
+		}
+		override.Windows = append(override.Windows, w.Name)
+		if w.TargetValue != nil && (override.TargetValue == nil || *w.TargetValue < *override.TargetValue) {
+			override.TargetValue = w.TargetValue
+		}
+		if w.MinValue != nil && (override.MinValue == nil || *w.MinValue > *override.MinValue) {
+			override.MinValue = w.MinValue
+		}
+		override.ForceActive = override.ForceActive || w.ForceActive
+	}
+	return override
+}
+
+// ParsePolicy reads windows from metadata keys of the form
+// schedule.<name>.<field>.
+func ParsePolicy(metadata map[string]string) (Policy, error) {
+	fields := map[string]map[string]string{}
+	for key, value := range metadata {
+		if !strings.HasPrefix(key, "schedule.") {
+			continue
+		}
+		rest := strings.TrimPrefix(key, "schedule.")
+		i := strings.LastIndex(rest, ".")
+		if i <= 0 {
+			return Policy{}, fmt.Errorf("invalid schedule key %q, expected schedule.<name>.<field>", key)
+		}
+		name, field := rest[:i], rest[i+1:]
+		if fields[name] == nil {
+			fields[name] = map[string]string{}
+		}
+		fields[name][field] = value
+	}
+
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var policy Policy
+	for _, name := range names {
+		window, err := parseWindow(name, fields[name])
+		if err != nil {
+			return Policy{}, err
+		}
+		policy.Windows = append(policy.Windows, window)
+	}
+	return policy, nil
+}
+
+func parseWindow(name string, fields map[string]string) (Window, error) {
+	window := Window{Name: name, Location: time.UTC}
+
+	for field, value := range fields {
+		switch field {
+		case "start", "duration", "timezone", "targetValue", "minValue", "forceActive":
+		default:
+			return Window{}, fmt.Errorf("schedule %q: unknown field %q", name, field)
+		}
+		if strings.TrimSpace(value) == "" {
+			return Window{}, fmt.Errorf("schedule %q: %s must not be empty", name, field)
+		}
+	}
+
+	if fields["start"] == "" {
+		return Window{}, fmt.Errorf("schedule %q: start is required", name)
+	}
+	start, err := ParseCron(fields["start"])
+	if err != nil {
+		return Window{}, fmt.Errorf("schedule %q: %v", name, err)
+	}
+	window.Start = start
+
+	if fields["duration"] == "" {
+		return Window{}, fmt.Errorf("schedule %q: duration is required", name)
+	}
+	duration, err := time.ParseDuration(fields["duration"])
+	if err != nil || duration <= 0 || duration > maxWindowDuration {
+		return Window{}, fmt.Errorf("schedule %q: duration must be a positive duration up to %v: %q",
+			name, maxWindowDuration, fields["duration"])
+	}
+	window.Duration = duration
+
+	if tz := fields["timezone"]; tz != "" {
+		location, err := time.LoadLocation(tz)
+		if err != nil {
+			return Window{}, fmt.Errorf("schedule %q: invalid timezone %q: %v", name, tz, err)
+		}
+		window.Location = location
+	}
+
+	if s := fields["targetValue"]; s != "" {
+		v, err := strconv.ParseFloat(s, 64)
+		if err != nil || v <= 0 || math.IsNaN(v) || math.IsInf(v, 0) {
+			return Window{}, fmt.Errorf("schedule %q: targetValue must be a positive finite number: %q", name, s)
+		}
+		window.TargetValue = &v
+	}
+
+	if s := fields["minValue"]; s != "" {
+		v, err := strconv.ParseFloat(s, 64)
+		if err != nil || math.IsNaN(v) || math.IsInf(v, 0) {
+			return Window{}, fmt.Errorf("schedule %q: minValue must be a finite number: %q", name, s)
+		}
+		window.MinValue = &v
+	}
+
+	if s := fields["forceActive"]; s != "" {
+		v, err := strconv.ParseBool(s)
+		if err != nil {
+			return Window{}, fmt.Errorf("schedule %q: forceActive must be a boolean: %q", name, s)
+		}
+		window.ForceActive = v
+	}
+
+	if window.TargetValue == nil && window.MinValue == nil && !window.ForceActive {
+		return Window{}, fmt.Errorf("schedule %q: must set targetValue, minValue or forceActive", name)
+	}
+
+	return window, nil
+}
@@ -0,0 +1,99 @@
+package schedule
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCronPrevious(t *testing.T) {
+	tests := []struct {
+		expr  string
+		now   time.Time
+		want  time.Time
+		found bool
+	}{
+		{"0 8 * * 1-5", time.Date(2026, 7, 15, 9, 30, 0, 0, time.UTC), time.Date(2026, 7, 15, 8, 0, 0, 0, time.UTC), true},
+		{"0 8 * * mon-fri", time.Date(2026, 7, 19, 9, 30, 0, 0, time.UTC), time.Date(2026, 7, 17, 8, 0, 0, 0, time.UTC), true},
+		{"*/15 * * * *", time.Date(2026, 7, 15, 9, 44, 59, 0, time.UTC), time.Date(2026, 7, 15, 9, 30, 0, 0, time.UTC), true},
+		{"0 0 1 jan *", time.Date(2026, 1, 1, 13, 0, 0, 0, time.UTC), time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC), true},
+		{"0 0 1 jan *", time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC), time.Time{}, false},
+		{"30 6 * * 7", time.Date(2026, 7, 19, 7, 0, 0, 0, time.UTC), time.Date(2026, 7, 19, 6, 30, 0, 0, time.UTC), true},
+		// A stepped "*" day-of-month is unrestricted: odd Mondays only.
+		{"0 9 */2 * 1", time.Date(2026, 7, 16, 10, 0, 0, 0, time.UTC), time.Date(2026, 7, 13, 9, 0, 0, 0, time.UTC), true},
+		{"0 9 */2 * 1", time.Date(2026, 7, 21, 10, 0, 0, 0, time.UTC), time.Date(2026, 7, 13, 9, 0, 0, 0, time.UTC), true},
+		{"0 9 1,15 * 1", time.Date(2026, 7, 16, 10, 0, 0, 0, time.UTC), time.Date(2026, 7, 15, 9, 0, 0, 0, time.UTC), true},
+	}
+
+	for _, tt := range tests {
+		cron, err := ParseCron(tt.expr)
+		if err != nil {
+			t.Fatalf("ParseCron(%q) error = %v", tt.expr, err)
+		}
+		got, found := cron.Previous(tt.now, 31*24*time.Hour)
+		if found != tt.found || !got.Equal(tt.want) {
+			t.Fatalf("%q.Previous(%v) = %v, %t, want %v, %t", tt.expr, tt.now, got, found, tt.want, tt.found)
+		}
+	}
+}
+
+func TestParseCronErrors(t *testing.T) {
+	for _, expr := range []string{"", "* * * *", "60 * * * *", "* 24 * * *", "5-1 * * * *", "*/0 * * * *", "* * * foo *"} {
+		if _, err := ParseCron(expr); err == nil {
+			t.Fatalf("ParseCron(%q) succeeded, want error", expr)
+		}
+	}
+}
+
+func TestPolicyEvaluate(t *testing.T) {
+	policy, err := ParsePolicy(map[string]string{
+		"schedule.business.start":       "0 8 * * 1-5",
+		"schedule.business.duration":    "10h",
+		"schedule.business.timezone":    "Europe/Moscow",
+		"schedule.business.targetValue": "50",
+		"schedule.business.minValue":    "10",
+		"schedule.prewarm.start":        "30 7 * * 1-5",
+		"schedule.prewarm.duration":     "1h",
+		"schedule.prewarm.timezone":     "Europe/Moscow",
+		"schedule.prewarm.minValue":     "200",
+		"schedule.prewarm.forceActive":  "true",
+	})
+	if err != nil {
+		t.Fatalf("ParsePolicy() error = %v", err)
+	}
+
+	// 08:15 MSK on a Wednesday: both windows are active.
+	override := policy.Evaluate(time.Date(2026, 7, 15, 5, 15, 0, 0, time.UTC))
+	if strings.Join(override.Windows, ",") != "business,prewarm" {
+		t.Fatalf("Windows = %v", override.Windows)
+	}
+	if *override.TargetValue != 50 || *override.MinValue != 200 || !override.ForceActive {
+		t.Fatalf("override = target %v, min %v, force %t", *override.TargetValue, *override.MinValue, override.ForceActive)
+	}
+
+	// 19:00 MSK: outside both windows.
+	if override := policy.Evaluate(time.Date(2026, 7, 15, 16, 0, 0, 0, time.UTC)); override.Active() {
+		t.Fatalf("unexpected active windows %v", override.Windows)
+	}
+}
+
+func TestParsePolicyErrors(t *testing.T) {
+	tests := []struct {
+		metadata map[string]string
+		want     string
+	}{
+		{map[string]string{"schedule.a.duration": "1h", "schedule.a.forceActive": "true"}, "start is required"},
+		{map[string]string{"schedule.a.start": "0 8 * * *", "schedule.a.forceActive": "true"}, "duration is required"},
+		{map[string]string{"schedule.a.start": "0 8 * * *", "schedule.a.duration": "1h"}, "must set"},
+		{map[string]string{"schedule.a.start": "0 8 * * *", "schedule.a.duration": "1h", "schedule.a.timezone": "Mars/Olympus", "schedule.a.forceActive": "true"}, "timezone"},
+		{map[string]string{"schedule.a.start": "0 8 * * *", "schedule.a.duration": "1h", "schedule.a.target": "5"}, "unknown field"},
+		{map[string]string{"schedule.a.start": "0 8 * * *", "schedule.a.duration": "1h", "schedule.a.targetValue": "0"}, "targetValue"},
+		{map[string]string{"schedule.start": "0 8 * * *"}, "invalid schedule key"},
+	}
+
+	for _, tt := range tests {
+		if _, err := ParsePolicy(tt.metadata); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Fatalf("ParsePolicy(%v) error = %v, want substring %q", tt.metadata, err, tt.want)
+		}
+	}
+}
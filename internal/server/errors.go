@@ -0,0 +1,72 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"keda-external-scaler-yc-monitoring/internal/breaker"
+	"keda-external-scaler-yc-monitoring/internal/metrics"
+)
+
+var apiErrorCodes = map[metrics.APIErrorKind]codes.Code{
+	metrics.APIErrorInvalidQuery:     codes.InvalidArgument,
+	metrics.APIErrorUnauthenticated:  codes.Unauthenticated,
+	metrics.APIErrorPermissionDenied: codes.PermissionDenied,
+	metrics.APIErrorNotFound:         codes.NotFound,
+	metrics.APIErrorQuotaExceeded:    codes.ResourceExhausted,
+	metrics.APIErrorUnavailable:      codes.Unavailable,
+	metrics.APIErrorUnknown:          codes.Unknown,
+}
+
+// queryError converts a QueryMetric failure into a gRPC status so that KEDA
+// sees a meaningful code instead of codes.Unknown.
+func queryError(err error) error {
+	msg := fmt.Sprintf("failed to query metric: %v", err)
+
+	var apiErr *metrics.APIError
+	switch {
+	case errors.As(err, &apiErr):
+		st := status.New(apiErrorCodes[apiErr.Kind], msg)
+		var detail *status.Status
+		var detailErr error
+		switch apiErr.Kind {
+		case metrics.APIErrorInvalidQuery:
+			detail, detailErr = st.WithDetails(&errdetails.BadRequest{
+				FieldViolations: []*errdetails.BadRequest_FieldViolation{{
+					Field:       "query",
+					Description: apiErr.Message,
+				}},
+			})
+		case metrics.APIErrorQuotaExceeded:
+			detail, detailErr = st.WithDetails(&errdetails.QuotaFailure{
+				Violations: []*errdetails.QuotaFailure_Violation{{
+					Subject:     "folder:" + apiErr.FolderID,
+					Description: apiErr.Message,
+				}},
+			})
+		}
+		if detail != nil && detailErr == nil {
+			st = detail
+		}
+		return st.Err()
+	case errors.Is(err, breaker.ErrOpen):
+		return status.Error(codes.Unavailable, msg)
+	case errors.Is(err, context.DeadlineExceeded):
+		return status.Error(codes.DeadlineExceeded, msg)
+	case errors.Is(err, context.Canceled):
+		return status.Error(codes.Canceled, msg)
+	default:
+		return status.Error(codes.Unknown, msg)
+	}
+}
+
+// invalidMetadata reports ScaledObject metadata that cannot be used as
+// codes.InvalidArgument, which KEDA does not retry as a transient failure.
+func invalidMetadata(err error) error {
+	return status.Error(codes.InvalidArgument, err.Error())
+}
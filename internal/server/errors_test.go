@@ -0,0 +1,128 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	protos "keda-external-scaler-yc-monitoring/gen/proto/externalscaler"
+	"keda-external-scaler-yc-monitoring/internal/breaker"
+	"keda-external-scaler-yc-monitoring/internal/config"
+	"keda-external-scaler-yc-monitoring/internal/httpclient"
+	"keda-external-scaler-yc-monitoring/internal/metrics"
+)
+
+func TestQueryErrorCodes(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want codes.Code
+	}{
+		{"permission", &metrics.APIError{Kind: metrics.APIErrorPermissionDenied}, codes.PermissionDenied},
+		{"unavailable", &metrics.APIError{Kind: metrics.APIErrorUnavailable}, codes.Unavailable},
+		{"wrapped", fmt.Errorf("SLO error query: %w", &metrics.APIError{Kind: metrics.APIErrorNotFound}), codes.NotFound},
+		{"circuit open", fmt.Errorf("suspended: %w", breaker.ErrOpen), codes.Unavailable},
+		{"deadline", context.DeadlineExceeded, codes.DeadlineExceeded},
+		{"other", fmt.Errorf("no valid metric data available"), codes.Unknown},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := status.Code(queryError(tt.err)); got != tt.want {
+				t.Fatalf("code = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestQueryErrorDetails(t *testing.T) {
+	st := status.Convert(queryError(&metrics.APIError{Kind: metrics.APIErrorInvalidQuery, Message: "bad selector"}))
+	if st.Code() != codes.InvalidArgument || len(st.Details()) != 1 {
+		t.Fatalf("status = %v with %d details", st.Code(), len(st.Details()))
+	}
+	badRequest, ok := st.Details()[0].(*errdetails.BadRequest)
+	if !ok || badRequest.FieldViolations[0].Field != "query" || badRequest.FieldViolations[0].Description != "bad selector" {
+		t.Fatalf("details = %v", st.Details())
+	}
+
+	st = status.Convert(queryError(&metrics.APIError{Kind: metrics.APIErrorQuotaExceeded, FolderID: "b1g", Message: "read quota"}))
+	quota, ok := st.Details()[0].(*errdetails.QuotaFailure)
+	if st.Code() != codes.ResourceExhausted || !ok || quota.Violations[0].Subject != "folder:b1g" {
+		t.Fatalf("quota status = %v, details = %v", st.Code(), st.Details())
+	}
+}
+
+func TestGetMetricsTimeout(t *testing.T) {
+	done := make(chan struct{})
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-done:
+		}
+	}))
+	defer api.Close()
+	defer close(done)
+
+	cfg := &config.Config{MonitoringEndpoint: api.URL, APITimeout: time.Minute}
+	server := NewExternalScalerServerWithBackend(metrics.NewClient(staticToken("test-token"), cfg, httpclient.New(cfg)), cfg)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err := server.GetMetrics(ctx, &protos.GetMetricsRequest{
+		MetricName: "m",
+		ScaledObjectRef: &protos.ScaledObjectRef{Name: "api", ScalerMetadata: map[string]string{
+			"logLevel": "none",
+			"query":    "q",
+			"folderId": "folder",
+		}},
+	})
+	if got := status.Code(err); got != codes.DeadlineExceeded {
+		t.Fatalf("code = %s, want %s (err = %v)", got, codes.DeadlineExceeded, err)
+	}
+}
+
+func TestInvalidMetadataCodes(t *testing.T) {
+	server := newTestServer(t, map[string]float64{"q": 1})
+	tests := []struct {
+		name     string
+		metadata map[string]string
+	}{
+		{"template", map[string]string{"folderId": "{{.Nope}}"}},
+		{"schedule", map[string]string{"schedule.x.start": "not cron", "schedule.x.duration": "1h", "schedule.x.minValue": "1"}},
+		{"replicas formula", map[string]string{"replicas.formula": "value +"}},
+		{"outlier filter", map[string]string{"outlierFilter": "bogus"}},
+		{"downsampling", map[string]string{"downsampling.mode": "auto", "downsampling.maxPoints": "100"}},
+		{"replicas evaluation", map[string]string{"replicas.formula": "value / 0"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			metadata := map[string]string{"logLevel": "none", "query": "q", "folderId": "folder"}
+			for k, v := range tt.metadata {
+				metadata[k] = v
+			}
+			ref := &protos.ScaledObjectRef{Name: "api", ScalerMetadata: metadata}
+			_, err := server.GetMetrics(context.Background(), &protos.GetMetricsRequest{MetricName: "m", ScaledObjectRef: ref})
+			if got := status.Code(err); got != codes.InvalidArgument {
+				t.Fatalf("GetMetrics() code = %s, want %s (err = %v)", got, codes.InvalidArgument, err)
+			}
+			_, err = server.IsActive(context.Background(), ref)
+			if got := status.Code(err); got != codes.InvalidArgument {
+				t.Fatalf("IsActive() code = %s, want %s (err = %v)", got, codes.InvalidArgument, err)
+			}
+		})
+	}
+
+	_, err := server.GetMetricSpec(context.Background(), &protos.ScaledObjectRef{Name: "api", ScalerMetadata: map[string]string{
+		"logLevel":         "none",
+		"replicas.formula": "value +",
+	}})
+	if got := status.Code(err); got != codes.InvalidArgument {
+		t.Fatalf("GetMetricSpec() code = %s, want %s (err = %v)", got, codes.InvalidArgument, err)
+	}
+}
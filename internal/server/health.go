@@ -0,0 +1,36 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+
+	"keda-external-scaler-yc-monitoring/internal/breaker"
+)
+
+// circuitReporter is implemented by backends that guard their reads with
+// circuit breakers.
+type circuitReporter interface {
+	CircuitStates() map[string]breaker.State
+}
+
+// ServeHealth reports the process as healthy together with the state of the
+// circuit breakers. Open circuits do not fail the check: restarting the scaler
+// would not fix an unavailable folder or API.
+func (s *ExternalScalerServer) ServeHealth(w http.ResponseWriter, _ *http.Request) {
+	var states map[string]breaker.State
+	if reporter, ok := s.backend.(circuitReporter); ok {
+		states = reporter.CircuitStates()
+	}
+	names := make([]string, 0, len(states))
+	for name := range states {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("OK\n"))
+	for _, name := range names {
+		fmt.Fprintf(w, "circuit %s: %s\n", name, states[name])
+	}
+}
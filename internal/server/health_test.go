@@ -0,0 +1,55 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	protos "keda-external-scaler-yc-monitoring/gen/proto/externalscaler"
+	"keda-external-scaler-yc-monitoring/internal/config"
+	"keda-external-scaler-yc-monitoring/internal/httpclient"
+	"keda-external-scaler-yc-monitoring/internal/metrics"
+)
+
+type staticToken string
+
+func (s staticToken) GetToken(context.Context) (string, error) {
+	return string(s), nil
+}
+
+func TestServeHealthReportsOpenCircuit(t *testing.T) {
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer api.Close()
+
+	cfg := &config.Config{
+		MonitoringEndpoint:      api.URL,
+		APITimeout:              time.Second,
+		BreakerFailureThreshold: 1,
+		BreakerOpenTimeout:      time.Minute,
+	}
+	server := NewExternalScalerServerWithBackend(metrics.NewClient(staticToken("test-token"), cfg, httpclient.New(cfg)), cfg)
+
+	req := &protos.GetMetricsRequest{
+		MetricName: "m",
+		ScaledObjectRef: &protos.ScaledObjectRef{Name: "api", ScalerMetadata: map[string]string{
+			"logLevel": "none",
+			"query":    "q",
+			"folderId": "revoked",
+		}},
+	}
+	if _, err := server.GetMetrics(context.Background(), req); err == nil {
+		t.Fatal("expected GetMetrics to fail with 403")
+	}
+
+	rec := httptest.NewRecorder()
+	server.ServeHealth(rec, httptest.NewRequest(http.MethodGet, "/health", nil))
+
+	want := "OK\ncircuit folder/revoked: open\ncircuit iam: closed\n"
+	if rec.Code != http.StatusOK || rec.Body.String() != want {
+		t.Fatalf("health = %d %q, want 200 %q", rec.Code, rec.Body.String(), want)
+	}
+}
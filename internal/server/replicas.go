@@ -0,0 +1,95 @@
+package server
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"keda-external-scaler-yc-monitoring/internal/expr"
+)
+
+// replicasTargetValue is the target reported in desired-replicas mode. With
+// an AverageValue target of 1 the HPA computes ceil(value / 1), so reporting
+// the replica count as the metric value makes the HPA land on it.
+const replicasTargetValue = 1
+
+type replicaPolicy struct {
+	formula  *expr.Expression
+	rounding func(float64) float64
+	min      *int64
+	max      *int64
+}
+
+func parseReplicaPolicy(metadata map[string]string) (*replicaPolicy, error) {
+	formula := strings.TrimSpace(metadata["replicas.formula"])
+	if formula == "" {
+		return nil, nil
+	}
+
+	compiled, err := expr.Parse(formula, "value", "target")
+	if err != nil {
+		return nil, err
+	}
+	policy := &replicaPolicy{formula: compiled}
+
+	switch strings.ToLower(strings.TrimSpace(metadata["replicas.rounding"])) {
+	case "", "ceil", "up":
+		policy.rounding = math.Ceil
+	case "floor", "down":
+		policy.rounding = math.Floor
+	case "round", "nearest":
+		policy.rounding = math.Round
+	default:
+		return nil, fmt.Errorf("unsupported replicas.rounding: %q", metadata["replicas.rounding"])
+	}
+
+	for _, bound := range []struct {
+		key   string
+		value **int64
+	}{
+		{"replicas.min", &policy.min},
+		{"replicas.max", &policy.max},
+	} {
+		s := strings.TrimSpace(metadata[bound.key])
+		if s == "" {
+			continue
+		}
+		v, err := strconv.ParseInt(s, 10, 64)
+		if err != nil || v < 0 {
+			return nil, fmt.Errorf("%s must be a non-negative integer: %q", bound.key, s)
+		}
+		*bound.value = &v
+	}
+	if policy.min != nil && policy.max != nil && *policy.min > *policy.max {
+		return nil, fmt.Errorf("replicas.min (%d) must not be greater than replicas.max (%d)", *policy.min, *policy.max)
+	}
+
+	return policy, nil
+}
+
+func (p *replicaPolicy) desiredReplicas(value, targetValue float64) (int64, error) {
+	raw, err := p.formula.Eval(map[string]float64{"value": value, "target": targetValue})
+	if err != nil {
+		return 0, err
+	}
+
+	// Clamp before converting: a float beyond the int64 range has no
+	// defined conversion. Replica counts are int32 in Kubernetes.
+	limit := int64(math.MaxInt32)
+	if p.max != nil {
+		limit = *p.max
+	}
+	rounded := p.rounding(raw)
+	var replicas int64
+	switch {
+	case rounded >= float64(limit):
+		replicas = limit
+	case rounded > 0:
+		replicas = int64(rounded)
+	}
+	if p.min != nil && replicas < *p.min {
+		replicas = *p.min
+	}
+	return replicas, nil
+}
@@ -0,0 +1,150 @@
+package server
+
+import (
+	"context"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	protos "keda-external-scaler-yc-monitoring/gen/proto/externalscaler"
+)
+
+func TestDesiredReplicas(t *testing.T) {
+	tests := []struct {
+		name     string
+		metadata map[string]string
+		value    float64
+		want     int64
+	}{
+		{name: "ceil by default", metadata: map[string]string{"replicas.formula": "value / 50"}, value: 101, want: 3},
+		{name: "floor", metadata: map[string]string{"replicas.formula": "value / 50", "replicas.rounding": "floor"}, value: 149, want: 2},
+		{name: "round", metadata: map[string]string{"replicas.formula": "value / 50", "replicas.rounding": "round"}, value: 125, want: 3},
+		{name: "target variable", metadata: map[string]string{"replicas.formula": "value / target"}, value: 400, want: 5},
+		{name: "min bound", metadata: map[string]string{"replicas.formula": "ceil(value / 50)", "replicas.min": "2"}, value: 0, want: 2},
+		{name: "max bound", metadata: map[string]string{"replicas.formula": "ceil(value / 50)", "replicas.max": "10"}, value: 5000, want: 10},
+		{name: "negative clamps to zero", metadata: map[string]string{"replicas.formula": "value - 10"}, value: 3, want: 0},
+		{name: "huge value clamps to int32", metadata: map[string]string{"replicas.formula": "value"}, value: 1e300, want: math.MaxInt32},
+		{name: "huge value clamps to max", metadata: map[string]string{"replicas.formula": "value", "replicas.max": "10"}, value: 1e300, want: 10},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy, err := parseReplicaPolicy(tt.metadata)
+			if err != nil {
+				t.Fatalf("parseReplicaPolicy() error = %v", err)
+			}
+			got, err := policy.desiredReplicas(tt.value, 80)
+			if err != nil {
+				t.Fatalf("desiredReplicas() error = %v", err)
+			}
+			if got != tt.want {
+				t.Fatalf("desiredReplicas(%v) = %d, want %d", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseReplicaPolicyErrors(t *testing.T) {
+	tests := []struct {
+		metadata map[string]string
+		want     string
+	}{
+		{map[string]string{"replicas.formula": "queue / 50"}, "unknown variable"},
+		{map[string]string{"replicas.formula": "value", "replicas.rounding": "up-ish"}, "replicas.rounding"},
+		{map[string]string{"replicas.formula": "value", "replicas.min": "-1"}, "replicas.min"},
+		{map[string]string{"replicas.formula": "value", "replicas.min": "5", "replicas.max": "2"}, "must not be greater"},
+	}
+
+	for _, tt := range tests {
+		if _, err := parseReplicaPolicy(tt.metadata); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Fatalf("parseReplicaPolicy(%v) error = %v, want substring %q", tt.metadata, err, tt.want)
+		}
+	}
+
+	if policy, err := parseReplicaPolicy(map[string]string{}); policy != nil || err != nil {
+		t.Fatalf("parseReplicaPolicy(empty) = %v, %v, want disabled", policy, err)
+	}
+}
+
+func TestGetMetricSpecReplicasMode(t *testing.T) {
+	server := &ExternalScalerServer{}
+	resp, err := server.GetMetricSpec(context.Background(), &protos.ScaledObjectRef{
+		Name: "demo",
+		ScalerMetadata: map[string]string{
+			"logLevel":         "none",
+			"targetValue":      "100",
+			"replicas.formula": "ceil(value / 50)",
+		},
+	})
+	if err != nil {
+		t.Fatalf("GetMetricSpec() error = %v", err)
+	}
+	if got := resp.MetricSpecs[0].TargetSizeFloat; got != replicasTargetValue {
+		t.Fatalf("TargetSizeFloat = %v, want %v", got, replicasTargetValue)
+	}
+}
+
+func TestReplicasModeLargeValue(t *testing.T) {
+	server := newTestServer(t, map[string]float64{"huge": 1e300})
+	ref := &protos.ScaledObjectRef{Name: "demo", Namespace: "jobs", ScalerMetadata: map[string]string{
+		"logLevel":         "none",
+		"query":            "huge",
+		"folderId":         "folder",
+		"replicas.formula": "value / target",
+	}}
+
+	resp, err := server.GetMetrics(context.Background(), &protos.GetMetricsRequest{MetricName: "m", ScaledObjectRef: ref})
+	if err != nil {
+		t.Fatalf("GetMetrics() error = %v", err)
+	}
+	if got := resp.MetricValues[0].MetricValueFloat; got != math.MaxInt32 {
+		t.Fatalf("GetMetrics() = %v, want %d", got, math.MaxInt32)
+	}
+	if active, err := server.IsActive(context.Background(), ref); err != nil || !active.Result {
+		t.Fatalf("IsActive() = %v, %v, want active", active, err)
+	}
+
+	ref.ScalerMetadata["targetValue"] = "-1"
+	if _, err := server.IsActive(context.Background(), ref); status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("IsActive() with invalid targetValue error = %v, want InvalidArgument", err)
+	}
+	if _, err := server.GetMetrics(context.Background(), &protos.GetMetricsRequest{MetricName: "m", ScaledObjectRef: ref}); status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("GetMetrics() with invalid targetValue error = %v, want InvalidArgument", err)
+	}
+}
+
+func TestReplicasModeScheduleTarget(t *testing.T) {
+	server := newTestServer(t, map[string]float64{"rps": 100})
+	server.now = func() time.Time { return time.Date(2026, time.July, 15, 10, 0, 0, 0, time.UTC) }
+	ref := &protos.ScaledObjectRef{Name: "demo", Namespace: "jobs", ScalerMetadata: map[string]string{
+		"logLevel":               "none",
+		"query":                  "rps",
+		"folderId":               "folder",
+		"targetValue":            "10",
+		"replicas.formula":       "ceil(value / target)",
+		"schedule.p.start":       "0 9 * * *",
+		"schedule.p.duration":    "2h",
+		"schedule.p.targetValue": "5",
+	}}
+
+	resp, err := server.GetMetrics(context.Background(), &protos.GetMetricsRequest{MetricName: "m", ScaledObjectRef: ref})
+	if err != nil {
+		t.Fatalf("GetMetrics() error = %v", err)
+	}
+	if got := resp.MetricValues[0].MetricValueFloat; got != 20 {
+		t.Fatalf("GetMetrics() inside the window = %v, want 20 replicas for the scheduled target", got)
+	}
+
+	server.now = func() time.Time { return time.Date(2026, time.July, 15, 12, 0, 0, 0, time.UTC) }
+	resp, err = server.GetMetrics(context.Background(), &protos.GetMetricsRequest{MetricName: "m", ScaledObjectRef: ref})
+	if err != nil {
+		t.Fatalf("GetMetrics() error = %v", err)
+	}
+	if got := resp.MetricValues[0].MetricValueFloat; got != 10 {
+		t.Fatalf("GetMetrics() outside the window = %v, want 10 replicas", got)
+	}
+}
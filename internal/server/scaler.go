@@ -5,30 +5,64 @@ import (
 	"fmt"
 	"math"
 	"strconv"
+	"sync"
+	"time"
 
 	protos "keda-external-scaler-yc-monitoring/gen/proto/externalscaler"
 	"keda-external-scaler-yc-monitoring/internal/auth"
 	"keda-external-scaler-yc-monitoring/internal/config"
+	"keda-external-scaler-yc-monitoring/internal/httpclient"
 	"keda-external-scaler-yc-monitoring/internal/logger"
 	"keda-external-scaler-yc-monitoring/internal/metrics"
+	"keda-external-scaler-yc-monitoring/internal/schedule"
+	"keda-external-scaler-yc-monitoring/internal/telemetry"
 )
 
 type ExternalScalerServer struct {
 	protos.UnimplementedExternalScalerServer
-	metricsClient *metrics.Client
-	config        *config.Config
+	backend   metrics.Backend
+	config    *config.Config
+	telemetry *telemetry.Registry
+	now       func() time.Time
+
+	shadows  sync.Map       // ScaledObjects with a shadow query in flight
+	shadowWG sync.WaitGroup // Running shadow queries
+
+	shadowMu     sync.Mutex
+	shadowSeries map[string]*shadowSeries // Exported shadow metrics by ScaledObject
 }
 
 func NewExternalScalerServer(keyPath string, cfg *config.Config) (*ExternalScalerServer, error) {
-	auth, err := auth.NewTokenProvider(keyPath, cfg)
+	httpClient := httpclient.New(cfg)
+
+	auth, err := auth.NewTokenProvider(keyPath, cfg, httpClient)
 	if err != nil {
 		return nil, err
 	}
 
+	return NewExternalScalerServerWithBackend(metrics.NewClient(auth, cfg, httpClient), cfg), nil
+}
+
+// NewExternalScalerServerWithBackend serves metrics read from backend instead
+// of the Monitoring API.
+func NewExternalScalerServerWithBackend(backend metrics.Backend, cfg *config.Config) *ExternalScalerServer {
 	return &ExternalScalerServer{
-		metricsClient: metrics.NewClient(auth, cfg),
-		config:        cfg,
-	}, nil
+		backend:   backend,
+		config:    cfg,
+		telemetry: telemetry.NewRegistry(),
+		now:       time.Now,
+	}
+}
+
+// SetClock makes the server evaluate queries and schedules at the times
+// returned by now instead of the current time, for replaying recorded data.
+func (s *ExternalScalerServer) SetClock(now func() time.Time) {
+	s.now = now
+}
+
+// Telemetry returns the registry holding the scaler's exported metrics.
+func (s *ExternalScalerServer) Telemetry() *telemetry.Registry {
+	return s.telemetry
 }
 
 func (s *ExternalScalerServer) IsActive(ctx context.Context, req *protos.ScaledObjectRef) (*protos.IsActiveResponse, error) {
@@ -37,23 +71,69 @@ func (s *ExternalScalerServer) IsActive(ctx context.Context, req *protos.ScaledO
 
 	log.Debug("IsActive called: name=%s, namespace=%s", req.Name, req.Namespace)
 
-	options := metrics.QueryOptions{
-		Query:             metadata["query"],
-		FolderID:          metadata["folderId"],
-		NaNStrategy:       metrics.ParseNaNStrategy(metadata["nanStrategy"]),
-		AggregationMethod: metrics.ParseAggregationMethod(metadata["aggregationMethod"]),
-		TimeWindow:        metadata["timeWindow"],
-		TimeWindowOffset:  metadata["timeWindowOffset"],
-		Downsampling:      metrics.ParseDownsamplingOptions(metadata),
+	metadata, err := s.renderMetadata(req, log)
+	if err != nil {
+		log.Error("Invalid metadata template: %v", err)
+		log.LogKEDAResponse("IsActive", false, 0, 0, err)
+		return nil, invalidMetadata(err)
+	}
+
+	targetValue, err := parseTargetValue(metadata["targetValue"])
+	if err != nil {
+		log.Error("Invalid targetValue: %v", err)
+		log.LogKEDAResponse("IsActive", false, 0, 0, err)
+		return nil, invalidMetadata(err)
+	}
+
+	override, err := s.scheduleOverride(metadata, log)
+	if err != nil {
+		log.Error("Invalid schedule: %v", err)
+		log.LogKEDAResponse("IsActive", false, 0, 0, err)
+		return nil, invalidMetadata(err)
+	}
+	if override.ForceActive {
+		log.Info("IsActive result: true (forced by schedule %v)", override.Windows)
+		log.LogKEDAResponse("IsActive", true, 0, 0, nil)
+		return &protos.IsActiveResponse{Result: true}, nil
+	}
+
+	replicas, err := parseReplicaPolicy(metadata)
+	if err != nil {
+		log.Error("Invalid replicas policy: %v", err)
+		log.LogKEDAResponse("IsActive", false, 0, 0, err)
+		return nil, invalidMetadata(err)
+	}
+
+	options, err := s.buildQueryOptions(req, metadata)
+	if err != nil {
+		log.Error("Invalid query options: %v", err)
+		log.LogKEDAResponse("IsActive", false, 0, 0, err)
+		return nil, invalidMetadata(err)
 	}
 
-	value, err := s.metricsClient.QueryMetric(ctx, options, log)
+	value, err := metrics.QueryMetric(ctx, s.backend, options, log)
 	if err != nil {
 		log.Error("Error querying metric: %v", err)
 		log.LogKEDAResponse("IsActive", false, 0, 0, err)
 		return &protos.IsActiveResponse{Result: false}, nil
 	}
 
+	if override.MinValue != nil && value < *override.MinValue {
+		log.Debug("Raising value %f to schedule minimum %f", value, *override.MinValue)
+		value = *override.MinValue
+	}
+
+	if replicas != nil {
+		desired, err := replicas.desiredReplicas(value, override.Target(targetValue))
+		if err != nil {
+			log.Error("Failed to compute desired replicas: %v", err)
+			log.LogKEDAResponse("IsActive", false, value, 0, err)
+			return nil, invalidMetadata(fmt.Errorf("failed to compute desired replicas: %v", err))
+		}
+		log.Debug("Desired replicas for value %f: %d", value, desired)
+		value = float64(desired)
+	}
+
 	result := value > 0
 	log.Info("IsActive result: %t (value: %f)", result, value)
 
@@ -71,7 +151,17 @@ func (s *ExternalScalerServer) GetMetricSpec(ctx context.Context, req *protos.Sc
 	targetValue, err := parseTargetValue(metadata["targetValue"])
 	if err != nil {
 		log.Error("Invalid targetValue: %v", err)
-		return nil, err
+		return nil, invalidMetadata(err)
+	}
+
+	replicas, err := parseReplicaPolicy(metadata)
+	if err != nil {
+		log.Error("Invalid replicas policy: %v", err)
+		return nil, invalidMetadata(err)
+	}
+	if replicas != nil {
+		log.Debug("Desired-replicas mode: reporting target %d instead of targetValue %f", replicasTargetValue, targetValue)
+		targetValue = replicasTargetValue
 	}
 
 	metricSpec := &protos.MetricSpec{
@@ -94,28 +184,66 @@ func (s *ExternalScalerServer) GetMetrics(ctx context.Context, req *protos.GetMe
 	log.Debug("GetMetrics called: name=%s, namespace=%s, metadata=%v",
 		req.ScaledObjectRef.Name, req.ScaledObjectRef.Namespace, metadata)
 
+	metadata, err := s.renderMetadata(req.ScaledObjectRef, log)
+	if err != nil {
+		log.Error("Invalid metadata template: %v", err)
+		return nil, invalidMetadata(err)
+	}
+
 	targetValue, err := parseTargetValue(metadata["targetValue"])
 	if err != nil {
 		log.Error("Invalid targetValue: %v", err)
-		return nil, err
+		return nil, invalidMetadata(err)
 	}
 
-	options := metrics.QueryOptions{
-		Query:                 metadata["query"],
-		FolderID:              metadata["folderId"],
-		NaNStrategy:           metrics.ParseNaNStrategy(metadata["nanStrategy"]),
-		AggregationMethod:     metrics.ParseAggregationMethod(metadata["aggregationMethod"]),
-		TimeSeriesAggregation: metrics.ParseOptionalAggregationMethod(metadata["timeSeriesAggregation"]),
-		TimeWindow:            metadata["timeWindow"],
-		TimeWindowOffset:      metadata["timeWindowOffset"],
-		Downsampling:          metrics.ParseDownsamplingOptions(metadata),
+	override, err := s.scheduleOverride(metadata, log)
+	if err != nil {
+		log.Error("Invalid schedule: %v", err)
+		return nil, invalidMetadata(err)
 	}
 
-	value, err := s.metricsClient.QueryMetric(ctx, options, log)
+	replicas, err := parseReplicaPolicy(metadata)
+	if err != nil {
+		log.Error("Invalid replicas policy: %v", err)
+		return nil, invalidMetadata(err)
+	}
+
+	options, err := s.buildQueryOptions(req.ScaledObjectRef, metadata)
+	if err != nil {
+		log.Error("Invalid query options: %v", err)
+		return nil, invalidMetadata(err)
+	}
+
+	value, err := metrics.QueryMetric(ctx, s.backend, options, log)
 	if err != nil {
 		log.Error("Failed to query metric: %v", err)
 		log.LogKEDAResponse("GetMetrics", false, 0, targetValue, err)
-		return nil, fmt.Errorf("failed to query metric: %v", err)
+		return nil, queryError(err)
+	}
+
+	s.compareShadow(metadata, telemetry.Labels{
+		"namespace":    req.ScaledObjectRef.Namespace,
+		"scaledObject": req.ScaledObjectRef.Name,
+	}, options, value, log)
+
+	if replicas != nil {
+		if override.MinValue != nil && value < *override.MinValue {
+			value = *override.MinValue
+		}
+		desired, err := replicas.desiredReplicas(value, override.Target(targetValue))
+		if err != nil {
+			log.Error("Failed to compute desired replicas: %v", err)
+			log.LogKEDAResponse("GetMetrics", false, value, targetValue, err)
+			return nil, invalidMetadata(fmt.Errorf("failed to compute desired replicas: %v", err))
+		}
+		log.Info("Desired replicas for value %f: %d", value, desired)
+		value, targetValue = float64(desired), replicasTargetValue
+	} else {
+		var effectiveTarget float64
+		value, effectiveTarget = applyScheduleOverride(value, targetValue, override)
+		if effectiveTarget != targetValue {
+			log.Debug("Schedule target %f replaces targetValue %f", effectiveTarget, targetValue)
+		}
 	}
 
 	log.Info("Returning metric value: %f for metric: %s", value, req.MetricName)
@@ -132,6 +260,64 @@ func (s *ExternalScalerServer) GetMetrics(ctx context.Context, req *protos.GetMe
 	}, nil
 }
 
+// buildQueryOptions parses the query options shared by IsActive and
+// GetMetrics, so that both handlers evaluate the same query.
+func (s *ExternalScalerServer) buildQueryOptions(ref *protos.ScaledObjectRef, metadata map[string]string) (metrics.QueryOptions, error) {
+	transform, err := metrics.ParseValueTransform(metadata)
+	if err != nil {
+		return metrics.QueryOptions{}, err
+	}
+	forecast, err := metrics.ParseForecastOptions(metadata)
+	if err != nil {
+		return metrics.QueryOptions{}, err
+	}
+	outliers, err := metrics.ParseOutlierOptions(metadata)
+	if err != nil {
+		return metrics.QueryOptions{}, err
+	}
+	histogram, err := metrics.ParseHistogramOptions(metadata)
+	if err != nil {
+		return metrics.QueryOptions{}, err
+	}
+	slo, err := metrics.ParseSLOOptions(metadata)
+	if err != nil {
+		return metrics.QueryOptions{}, err
+	}
+	baseline, err := metrics.ParseBaselineOptions(metadata)
+	if err != nil {
+		return metrics.QueryOptions{}, err
+	}
+	downsampling, err := metrics.ParseDownsamplingOptions(metadata)
+	if err != nil {
+		return metrics.QueryOptions{}, err
+	}
+
+	return metrics.QueryOptions{
+		Query:                  metadata["query"],
+		FolderID:               metadata["folderId"],
+		FallbackQuery:          metadata["fallbackQuery"],
+		FallbackFolderID:       metadata["fallbackFolderId"],
+		ScaledObject:           ref.Namespace + "/" + ref.Name,
+		Now:                    s.now(),
+		NaNStrategy:            metrics.ParseNaNStrategy(metadata["nanStrategy"]),
+		InfStrategy:            metrics.ParseInfStrategy(metadata["infStrategy"], metrics.ParseNaNStrategy(metadata["nanStrategy"])),
+		NaNMaxAge:              metrics.ParseNaNMaxAge(metadata["nanMaxAge"]),
+		AggregationMethod:      metrics.ParseAggregationMethod(metadata["aggregationMethod"]),
+		TimeSeriesAggregation:  metrics.ParseOptionalAggregationMethod(metadata["timeSeriesAggregation"]),
+		CrossSeriesAggregation: metrics.ParseOptionalAggregationMethod(metadata["crossSeriesAggregation"]),
+		AlignmentTolerance:     metrics.ParseAlignmentTolerance(metadata["alignmentTolerance"]),
+		TimeWindow:             metadata["timeWindow"],
+		TimeWindowOffset:       metadata["timeWindowOffset"],
+		Downsampling:           downsampling,
+		Transform:              transform,
+		Forecast:               forecast,
+		Outliers:               outliers,
+		Histogram:              histogram,
+		SLO:                    slo,
+		Baseline:               baseline,
+	}, nil
+}
+
 func parseTargetValue(value string) (float64, error) {
 	if value == "" {
 		return 80, nil
@@ -147,3 +333,29 @@ func parseTargetValue(value string) (float64, error) {
 
 	return target, nil
 }
+
+func (s *ExternalScalerServer) scheduleOverride(metadata map[string]string, log *logger.Logger) (schedule.Override, error) {
+	policy, err := schedule.ParsePolicy(metadata)
+	if err != nil {
+		return schedule.Override{}, err
+	}
+
+	override := policy.Evaluate(s.now())
+	if override.Active() {
+		log.Info("Schedule windows active: %v", override.Windows)
+	}
+	return override, nil
+}
+
+// applyScheduleOverride raises value to the schedule minimum and, because the
+// HPA keeps the targetValue from GetMetricSpec, rescales it so that the
+// replica count follows the scheduled target instead.
+func applyScheduleOverride(value, targetValue float64, override schedule.Override) (float64, float64) {
+	if override.MinValue != nil && value < *override.MinValue {
+		value = *override.MinValue
+	}
+	if override.TargetValue == nil {
+		return value, targetValue
+	}
+	return value * targetValue / *override.TargetValue, *override.TargetValue
+}
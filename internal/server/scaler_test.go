@@ -1,7 +1,17 @@
 package server
 
 import (
+	"context"
 	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	protos "keda-external-scaler-yc-monitoring/gen/proto/externalscaler"
+	"keda-external-scaler-yc-monitoring/internal/config"
+	"keda-external-scaler-yc-monitoring/internal/metrics"
+	"keda-external-scaler-yc-monitoring/internal/schedule"
 )
 
 func TestParseTargetValue(t *testing.T) {
@@ -32,3 +42,125 @@ func TestParseTargetValue(t *testing.T) {
 		})
 	}
 }
+
+func TestIsActiveForcedBySchedule(t *testing.T) {
+	server := &ExternalScalerServer{now: func() time.Time {
+		return time.Date(2026, time.July, 15, 7, 45, 0, 0, time.UTC)
+	}}
+	req := &protos.ScaledObjectRef{Name: "demo", ScalerMetadata: map[string]string{
+		"logLevel":                     "none",
+		"schedule.prewarm.start":       "30 7 * * 1-5",
+		"schedule.prewarm.duration":    "1h",
+		"schedule.prewarm.forceActive": "true",
+	}}
+
+	resp, err := server.IsActive(context.Background(), req)
+	if err != nil {
+		t.Fatalf("IsActive() error = %v", err)
+	}
+	if !resp.Result {
+		t.Fatalf("IsActive() = false, want true inside schedule window")
+	}
+}
+
+func TestApplyScheduleOverride(t *testing.T) {
+	target, minimum := 50.0, 300.0
+	tests := []struct {
+		name       string
+		override   schedule.Override
+		value      float64
+		wantValue  float64
+		wantTarget float64
+	}{
+		{name: "no override", value: 120, wantValue: 120, wantTarget: 100},
+		{name: "scheduled target", override: schedule.Override{TargetValue: &target}, value: 120, wantValue: 240, wantTarget: 50},
+		{name: "minimum value", override: schedule.Override{MinValue: &minimum}, value: 120, wantValue: 300, wantTarget: 100},
+		{name: "minimum above value with target", override: schedule.Override{TargetValue: &target, MinValue: &minimum}, value: 120, wantValue: 600, wantTarget: 50},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			value, effectiveTarget := applyScheduleOverride(tt.value, 100, tt.override)
+			if value != tt.wantValue || effectiveTarget != tt.wantTarget {
+				t.Fatalf("applyScheduleOverride() = %v, %v, want %v, %v", value, effectiveTarget, tt.wantValue, tt.wantTarget)
+			}
+		})
+	}
+}
+
+func TestGetMetricsFromBackend(t *testing.T) {
+	backend := metrics.NewMemoryBackend()
+	backend.Set("queue_depth", metrics.RawSeries{
+		Name:       "queue_depth",
+		Timestamps: []int64{1000, 2000},
+		Values:     []metrics.Value{metrics.FiniteValue(40), metrics.FiniteValue(60)},
+	})
+	backend.SetError("broken", &metrics.APIError{StatusCode: 400, Kind: metrics.APIErrorInvalidQuery, Message: "bad query"})
+	server := NewExternalScalerServerWithBackend(backend, &config.Config{})
+
+	req := &protos.GetMetricsRequest{
+		MetricName: "queue",
+		ScaledObjectRef: &protos.ScaledObjectRef{Name: "worker", Namespace: "jobs", ScalerMetadata: map[string]string{
+			"logLevel":          "none",
+			"query":             "queue_depth",
+			"folderId":          "folder",
+			"aggregationMethod": "max",
+		}},
+	}
+	resp, err := server.GetMetrics(context.Background(), req)
+	if err != nil {
+		t.Fatalf("GetMetrics() error = %v", err)
+	}
+	if got := resp.MetricValues[0].MetricValueFloat; got != 60 {
+		t.Fatalf("GetMetrics() = %v, want 60", got)
+	}
+
+	active, err := server.IsActive(context.Background(), req.ScaledObjectRef)
+	if err != nil || !active.Result {
+		t.Fatalf("IsActive() = %v, %v, want active", active, err)
+	}
+
+	req.ScaledObjectRef.ScalerMetadata["query"] = "broken"
+	if _, err := server.GetMetrics(context.Background(), req); status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("GetMetrics() error = %v, want InvalidArgument", err)
+	}
+}
+
+func TestIsActiveAndGetMetricsAgree(t *testing.T) {
+	backend := metrics.NewMemoryBackend()
+	backend.Set("queue_depth", metrics.RawSeries{
+		Name:       "queue_depth",
+		Timestamps: []int64{1000, 2000},
+		Values:     []metrics.Value{metrics.FiniteValue(0), metrics.FiniteValue(8)},
+	})
+	server := NewExternalScalerServerWithBackend(backend, &config.Config{})
+
+	// IsActive used to ignore timeSeriesAggregation and saw the max of 8
+	// while GetMetrics reported the per-series min of 0.
+	ref := &protos.ScaledObjectRef{Name: "worker", Namespace: "jobs", ScalerMetadata: map[string]string{
+		"logLevel":              "none",
+		"query":                 "queue_depth",
+		"folderId":              "folder",
+		"aggregationMethod":     "max",
+		"timeSeriesAggregation": "min",
+	}}
+	resp, err := server.GetMetrics(context.Background(), &protos.GetMetricsRequest{MetricName: "queue", ScaledObjectRef: ref})
+	if err != nil {
+		t.Fatalf("GetMetrics() error = %v", err)
+	}
+	if got := resp.MetricValues[0].MetricValueFloat; got != 0 {
+		t.Fatalf("GetMetrics() = %v, want 0", got)
+	}
+	active, err := server.IsActive(context.Background(), ref)
+	if err != nil || active.Result {
+		t.Fatalf("IsActive() = %v, %v, want inactive like GetMetrics", active, err)
+	}
+
+	options, err := server.buildQueryOptions(ref, ref.ScalerMetadata)
+	if err != nil {
+		t.Fatalf("buildQueryOptions() error = %v", err)
+	}
+	if options.TimeSeriesAggregation != metrics.AggregationMin || options.ScaledObject != "jobs/worker" {
+		t.Errorf("buildQueryOptions() = %+v", options)
+	}
+}
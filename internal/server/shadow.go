@@ -0,0 +1,139 @@
+package server
+
+import (
+	"context"
+	"math"
+	"time"
+
+	"keda-external-scaler-yc-monitoring/internal/logger"
+	"keda-external-scaler-yc-monitoring/internal/metrics"
+	"keda-external-scaler-yc-monitoring/internal/telemetry"
+)
+
+const (
+	defaultShadowTimeout = 30 * time.Second
+	// shadowIdleTimeout is how long the shadow metrics of a ScaledObject that
+	// is no longer polled, e.g. because it was deleted, stay exported.
+	shadowIdleTimeout = 10 * time.Minute
+)
+
+// shadowMetricNames are the metrics compareShadow exports per ScaledObject.
+var shadowMetricNames = []string{
+	"yc_scaler_primary_value",
+	"yc_scaler_shadow_value",
+	"yc_scaler_shadow_divergence",
+	"yc_scaler_shadow_relative_divergence",
+	"yc_scaler_shadow_errors_total",
+}
+
+type shadowSeries struct {
+	labels   telemetry.Labels
+	lastUsed time.Time
+}
+
+// compareShadow starts evaluating shadowQuery in the background with the
+// primary query's options and records how far it diverges from the primary
+// value. The shadow value is never returned to KEDA, and its failures and
+// latency do not affect scaling. A shadow query still running from the
+// previous call for the same ScaledObject is not started again.
+//
+// The exported metrics are removed once shadowQuery is dropped from the
+// ScaledObject, or after shadowIdleTimeout without a GetMetrics call for it.
+func (s *ExternalScalerServer) compareShadow(metadata map[string]string, labels telemetry.Labels, options metrics.QueryOptions, primary float64, log *logger.Logger) {
+	query := metadata["shadowQuery"]
+	s.trackShadow(options.ScaledObject, labels, query != "")
+	if query == "" {
+		return
+	}
+
+	shadow := options
+	shadow.Query = query
+	shadow.FallbackQuery = ""
+	shadow.FallbackFolderID = ""
+	// The shadow is compared as a plain query; SLO and baseline modes
+	// evaluate their own queries, not query.
+	shadow.SLO = metrics.SLOOptions{}
+	shadow.Baseline = metrics.BaselineOptions{}
+	if folderID := metadata["shadowFolderId"]; folderID != "" {
+		shadow.FolderID = folderID
+	}
+
+	if _, running := s.shadows.LoadOrStore(options.ScaledObject, struct{}{}); running {
+		log.Debug("Shadow query still running, skipping this comparison")
+		return
+	}
+	timeout := defaultShadowTimeout
+	if s.config != nil && s.config.APITimeout > 0 {
+		timeout = s.config.APITimeout
+	}
+
+	s.shadowWG.Add(1)
+	go func() {
+		defer s.shadowWG.Done()
+		defer s.shadows.Delete(options.ScaledObject)
+
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+		s.runShadow(ctx, shadow, labels, primary, log)
+	}()
+}
+
+func (s *ExternalScalerServer) runShadow(ctx context.Context, shadow metrics.QueryOptions, labels telemetry.Labels, primary float64, log *logger.Logger) {
+	value, err := metrics.QueryMetric(ctx, s.backend, shadow, log)
+	s.shadowMu.Lock()
+	defer s.shadowMu.Unlock()
+	// The metrics were dropped while the query ran.
+	if _, ok := s.shadowSeries[shadow.ScaledObject]; !ok {
+		return
+	}
+
+	if err != nil {
+		log.Warn("Shadow query failed: %v", err)
+		s.telemetry.AddCounter("yc_scaler_shadow_errors_total", "Failed shadow query evaluations.", labels, 1)
+		return
+	}
+
+	divergence := value - primary
+	relative := math.NaN()
+	if primary != 0 {
+		relative = divergence / math.Abs(primary)
+	}
+	log.Info("Shadow query value: %f, primary: %f, divergence: %f (relative %.2f%%)",
+		value, primary, divergence, relative*100)
+
+	s.telemetry.SetGauge("yc_scaler_primary_value", "Last value of the primary query.", labels, primary)
+	s.telemetry.SetGauge("yc_scaler_shadow_value", "Last value of the shadow query.", labels, value)
+	s.telemetry.SetGauge("yc_scaler_shadow_divergence", "Shadow value minus primary value.", labels, divergence)
+	s.telemetry.SetGauge("yc_scaler_shadow_relative_divergence", "Shadow divergence relative to the primary value.", labels, relative)
+}
+
+// trackShadow records that the ScaledObject was polled, with or without a
+// shadow query, and deletes the shadow metrics of ScaledObjects that no
+// longer have one or have not been polled for shadowIdleTimeout.
+func (s *ExternalScalerServer) trackShadow(key string, labels telemetry.Labels, active bool) {
+	s.shadowMu.Lock()
+	defer s.shadowMu.Unlock()
+
+	now := s.now()
+	if active {
+		if s.shadowSeries == nil {
+			s.shadowSeries = map[string]*shadowSeries{}
+		}
+		s.shadowSeries[key] = &shadowSeries{labels: labels, lastUsed: now}
+	} else if series, ok := s.shadowSeries[key]; ok {
+		s.deleteShadowLocked(key, series)
+	}
+
+	for k, series := range s.shadowSeries {
+		if now.Sub(series.lastUsed) >= shadowIdleTimeout {
+			s.deleteShadowLocked(k, series)
+		}
+	}
+}
+
+func (s *ExternalScalerServer) deleteShadowLocked(key string, series *shadowSeries) {
+	for _, name := range shadowMetricNames {
+		s.telemetry.Delete(name, series.labels)
+	}
+	delete(s.shadowSeries, key)
+}
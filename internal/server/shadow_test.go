@@ -0,0 +1,138 @@
+package server
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	protos "keda-external-scaler-yc-monitoring/gen/proto/externalscaler"
+	"keda-external-scaler-yc-monitoring/internal/config"
+	"keda-external-scaler-yc-monitoring/internal/metrics"
+	"keda-external-scaler-yc-monitoring/internal/telemetry"
+)
+
+// newTestServer serves each query from values; unknown queries get no data.
+func newTestServer(t *testing.T, values map[string]float64) *ExternalScalerServer {
+	t.Helper()
+	backend := metrics.NewMemoryBackend()
+	for query, value := range values {
+		backend.Set(query, metrics.RawSeries{
+			Name:       "m",
+			Timestamps: []int64{1000},
+			Values:     []metrics.Value{metrics.FiniteValue(value)},
+		})
+	}
+	return NewExternalScalerServerWithBackend(backend, &config.Config{})
+}
+
+func TestGetMetricsComparesShadowQuery(t *testing.T) {
+	server := newTestServer(t, map[string]float64{"primary": 100, "shadow": 110})
+	req := &protos.GetMetricsRequest{
+		MetricName: "m",
+		ScaledObjectRef: &protos.ScaledObjectRef{Name: "api", Namespace: "prod", ScalerMetadata: map[string]string{
+			"logLevel":    "none",
+			"query":       "primary",
+			"folderId":    "folder",
+			"shadowQuery": "shadow",
+		}},
+	}
+
+	resp, err := server.GetMetrics(context.Background(), req)
+	if err != nil {
+		t.Fatalf("GetMetrics() error = %v", err)
+	}
+	server.shadowWG.Wait()
+	if got := resp.MetricValues[0].MetricValueFloat; got != 100 {
+		t.Fatalf("GetMetrics() = %v, want the primary value 100", got)
+	}
+
+	labels := telemetry.Labels{"namespace": "prod", "scaledObject": "api"}
+	if v, _ := server.telemetry.Value("yc_scaler_shadow_divergence", labels); v != 10 {
+		t.Errorf("divergence = %v, want 10", v)
+	}
+	if v, _ := server.telemetry.Value("yc_scaler_shadow_relative_divergence", labels); v != 0.1 {
+		t.Errorf("relative divergence = %v, want 0.1", v)
+	}
+
+	// A failing shadow query is counted but does not fail GetMetrics.
+	req.ScaledObjectRef.ScalerMetadata["shadowQuery"] = "missing"
+	if _, err := server.GetMetrics(context.Background(), req); err != nil {
+		t.Fatalf("GetMetrics() with failing shadow error = %v", err)
+	}
+	server.shadowWG.Wait()
+	if v, _ := server.telemetry.Value("yc_scaler_shadow_errors_total", labels); v != 1 {
+		t.Errorf("shadow errors = %v, want 1", v)
+	}
+}
+
+func TestShadowQueryIgnoresBaseline(t *testing.T) {
+	server := newTestServer(t, map[string]float64{"primary": 100, "shadow": 110})
+	req := &protos.GetMetricsRequest{
+		MetricName: "m",
+		ScaledObjectRef: &protos.ScaledObjectRef{Name: "api", Namespace: "prod", ScalerMetadata: map[string]string{
+			"logLevel":        "none",
+			"query":           "primary",
+			"folderId":        "folder",
+			"baseline.offset": "7d",
+			"shadowQuery":     "shadow",
+		}},
+	}
+
+	// The request context ends with the call; the shadow query has its own.
+	ctx, cancel := context.WithCancel(context.Background())
+	resp, err := server.GetMetrics(ctx, req)
+	cancel()
+	if err != nil {
+		t.Fatalf("GetMetrics() error = %v", err)
+	}
+	server.shadowWG.Wait()
+
+	// The recording is the same a week ago, so the primary ratio is 1.
+	if got := resp.MetricValues[0].MetricValueFloat; got != 1 {
+		t.Fatalf("GetMetrics() = %v, want the baseline ratio 1", got)
+	}
+	labels := telemetry.Labels{"namespace": "prod", "scaledObject": "api"}
+	if v, _ := server.telemetry.Value("yc_scaler_shadow_value", labels); v != 110 {
+		t.Errorf("shadow value = %v, want the plain shadow query value 110", v)
+	}
+}
+
+func TestShadowMetricsAreRemoved(t *testing.T) {
+	now := time.Date(2026, time.March, 1, 12, 0, 0, 0, time.UTC)
+	server := newTestServer(t, map[string]float64{"primary": 100, "shadow": 110})
+	server.now = func() time.Time { return now }
+	request := func(name, shadowQuery string) {
+		t.Helper()
+		metadata := map[string]string{"logLevel": "none", "query": "primary", "folderId": "folder"}
+		if shadowQuery != "" {
+			metadata["shadowQuery"] = shadowQuery
+		}
+		req := &protos.GetMetricsRequest{MetricName: "m", ScaledObjectRef: &protos.ScaledObjectRef{Name: name, Namespace: "prod", ScalerMetadata: metadata}}
+		if _, err := server.GetMetrics(context.Background(), req); err != nil {
+			t.Fatalf("GetMetrics() error = %v", err)
+		}
+		server.shadowWG.Wait()
+	}
+	exported := func(name string) bool {
+		_, ok := server.telemetry.Value("yc_scaler_shadow_value", telemetry.Labels{"namespace": "prod", "scaledObject": name})
+		return ok
+	}
+
+	// Removing shadowQuery removes the metrics.
+	request("migrated", "shadow")
+	if !exported("migrated") {
+		t.Fatal("shadow metrics not exported")
+	}
+	request("migrated", "")
+	if exported("migrated") {
+		t.Error("shadow metrics still exported after shadowQuery was removed")
+	}
+
+	// A ScaledObject that is no longer polled expires.
+	request("deleted", "shadow")
+	now = now.Add(shadowIdleTimeout)
+	request("other", "shadow")
+	if exported("deleted") || !exported("other") {
+		t.Errorf("exported deleted=%t other=%t, want only other", exported("deleted"), exported("other"))
+	}
+}
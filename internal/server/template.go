@@ -0,0 +1,95 @@
+package server
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"text/template"
+
+	protos "keda-external-scaler-yc-monitoring/gen/proto/externalscaler"
+	"keda-external-scaler-yc-monitoring/internal/logger"
+)
+
+// templatedKeys are the metadata keys rendered as Go templates: every query
+// and folder ID the scaler reads.
+var templatedKeys = []string{
+	"query", "fallbackQuery", "shadowQuery", "slo.errorQuery", "slo.totalQuery",
+	"folderId", "fallbackFolderId", "shadowFolderId",
+}
+
+var folderIDKeys = map[string]bool{"folderId": true, "fallbackFolderId": true, "shadowFolderId": true}
+
+var folderIDPattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// templateData is what query and folder ID templates can refer to.
+type templateData struct {
+	Name      string
+	Namespace string
+	Metadata  map[string]string
+	Env       map[string]string // Only the variables listed in TemplateEnvVars
+}
+
+// renderMetadata returns the metadata with the query and folder ID templates
+// rendered, e.g. {{.Namespace}}, {{.Metadata.service}} or {{.Env.CLUSTER}}.
+// Referring to an unknown field, metadata key or environment variable is an
+// error, as is a template rendering to an empty query or an invalid folder ID.
+func (s *ExternalScalerServer) renderMetadata(ref *protos.ScaledObjectRef, log *logger.Logger) (map[string]string, error) {
+	metadata := ref.ScalerMetadata
+
+	var rendered map[string]string
+	for _, key := range templatedKeys {
+		text := metadata[key]
+		if !strings.Contains(text, "{{") {
+			continue
+		}
+
+		if rendered == nil {
+			rendered = make(map[string]string, len(metadata))
+			for k, v := range metadata {
+				rendered[k] = v
+			}
+		}
+
+		tmpl, err := template.New(key).Option("missingkey=error").Parse(text)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s template: %v", key, err)
+		}
+		var out strings.Builder
+		if err := tmpl.Execute(&out, s.templateData(ref)); err != nil {
+			return nil, fmt.Errorf("failed to render %s template: %v", key, err)
+		}
+
+		value := strings.TrimSpace(out.String())
+		switch {
+		case value == "":
+			return nil, fmt.Errorf("%s template rendered to an empty value", key)
+		case folderIDKeys[key] && !folderIDPattern.MatchString(value):
+			return nil, fmt.Errorf("%s template rendered to an invalid folder ID: %q", key, value)
+		}
+		log.Debug("Rendered %s template: %s -> %s", key, text, value)
+		rendered[key] = value
+	}
+
+	if rendered == nil {
+		return metadata, nil
+	}
+	return rendered, nil
+}
+
+func (s *ExternalScalerServer) templateData(ref *protos.ScaledObjectRef) templateData {
+	env := map[string]string{}
+	if s.config != nil {
+		for _, name := range s.config.TemplateEnvVars {
+			if value, ok := os.LookupEnv(name); ok {
+				env[name] = value
+			}
+		}
+	}
+	return templateData{
+		Name:      ref.Name,
+		Namespace: ref.Namespace,
+		Metadata:  ref.ScalerMetadata,
+		Env:       env,
+	}
+}
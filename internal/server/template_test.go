@@ -0,0 +1,155 @@
+package server
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	protos "keda-external-scaler-yc-monitoring/gen/proto/externalscaler"
+	"keda-external-scaler-yc-monitoring/internal/config"
+	"keda-external-scaler-yc-monitoring/internal/logger"
+	"keda-external-scaler-yc-monitoring/internal/metrics"
+)
+
+func TestRenderMetadata(t *testing.T) {
+	t.Setenv("CLUSTER", "prod-a")
+	t.Setenv("SECRET", "hidden")
+	server := NewExternalScalerServerWithBackend(metrics.NewMemoryBackend(), &config.Config{TemplateEnvVars: []string{"CLUSTER"}})
+	log := logger.NewLogger(map[string]string{"logLevel": "none"}, "test")
+
+	tests := []struct {
+		name     string
+		metadata map[string]string
+		want     map[string]string
+		wantErr  string
+	}{
+		{
+			name: "ref, metadata and env",
+			metadata: map[string]string{
+				"query":     `series_sum("rps"{service="{{.Metadata.service}}", namespace="{{.Namespace}}", cluster="{{.Env.CLUSTER}}", so="{{.Name}}"})`,
+				"folderId":  "{{index .Metadata \"folder.id\"}}",
+				"service":   "api",
+				"folder.id": "b1g123",
+			},
+			want: map[string]string{
+				"query":    `series_sum("rps"{service="api", namespace="jobs", cluster="prod-a", so="worker"})`,
+				"folderId": "b1g123",
+			},
+		},
+		{
+			name: "fallback, shadow and SLO keys",
+			metadata: map[string]string{
+				"query":            "q",
+				"folderId":         "b1g123",
+				"fallbackQuery":    `"rps_old"{service="{{.Metadata.service}}"}`,
+				"fallbackFolderId": "{{.Metadata.oldFolder}}",
+				"shadowQuery":      `"rps_v2"{namespace="{{.Namespace}}"}`,
+				"shadowFolderId":   "{{.Metadata.oldFolder}}",
+				"slo.errorQuery":   `"errors"{service="{{.Metadata.service}}"}`,
+				"slo.totalQuery":   `"requests"{service="{{.Metadata.service}}"}`,
+				"service":          "api",
+				"oldFolder":        "b1g456",
+			},
+			want: map[string]string{
+				"fallbackQuery":    `"rps_old"{service="api"}`,
+				"fallbackFolderId": "b1g456",
+				"shadowQuery":      `"rps_v2"{namespace="jobs"}`,
+				"shadowFolderId":   "b1g456",
+				"slo.errorQuery":   `"errors"{service="api"}`,
+				"slo.totalQuery":   `"requests"{service="api"}`,
+			},
+		},
+		{
+			name:     "invalid fallback folder ID",
+			metadata: map[string]string{"query": "q", "folderId": "b1g123", "fallbackFolderId": "{{.Metadata.folder}}", "folder": "b1g/x"},
+			wantErr:  "fallbackFolderId template rendered to an invalid folder ID",
+		},
+		{
+			name:     "unknown key in SLO query",
+			metadata: map[string]string{"query": "q", "folderId": "b1g123", "slo.errorQuery": "{{.Metadata.missing}}"},
+			wantErr:  "failed to render slo.errorQuery template",
+		},
+		{
+			name:     "plain values are kept",
+			metadata: map[string]string{"query": `"rps"{service="api"}`, "folderId": "b1g123"},
+			want:     map[string]string{"query": `"rps"{service="api"}`, "folderId": "b1g123"},
+		},
+		{
+			name:     "env var not whitelisted",
+			metadata: map[string]string{"query": "{{.Env.SECRET}}", "folderId": "b1g123"},
+			wantErr:  "failed to render query template",
+		},
+		{
+			name:     "unknown metadata key",
+			metadata: map[string]string{"query": "{{.Metadata.missing}}", "folderId": "b1g123"},
+			wantErr:  "failed to render query template",
+		},
+		{
+			name:     "syntax error",
+			metadata: map[string]string{"query": "{{.Namespace", "folderId": "b1g123"},
+			wantErr:  "invalid query template",
+		},
+		{
+			name:     "empty query",
+			metadata: map[string]string{"query": "{{.Metadata.service}}", "folderId": "b1g123", "service": ""},
+			wantErr:  "empty value",
+		},
+		{
+			name:     "invalid folder ID",
+			metadata: map[string]string{"query": "q", "folderId": "{{.Metadata.folder}}", "folder": "b1g123&x=1"},
+			wantErr:  "invalid folder ID",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ref := &protos.ScaledObjectRef{Name: "worker", Namespace: "jobs", ScalerMetadata: tt.metadata}
+			got, err := server.renderMetadata(ref, log)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("renderMetadata() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("renderMetadata() error = %v", err)
+			}
+			for key, want := range tt.want {
+				if got[key] != want {
+					t.Errorf("%s = %q, want %q", key, got[key], want)
+				}
+			}
+			if strings.Contains(tt.metadata["query"], "{{") && tt.metadata["query"] == got["query"] {
+				t.Error("renderMetadata() returned the query template unrendered")
+			}
+		})
+	}
+}
+
+func TestGetMetricsRendersQueryTemplate(t *testing.T) {
+	backend := metrics.NewMemoryBackend()
+	backend.Set(`"queue"{namespace="jobs"}`, metrics.RawSeries{Timestamps: []int64{1000}, Values: []metrics.Value{metrics.FiniteValue(7)}})
+	server := NewExternalScalerServerWithBackend(backend, &config.Config{})
+
+	metadata := map[string]string{
+		"logLevel": "none",
+		"query":    `"queue"{namespace="{{.Namespace}}"}`,
+		"folderId": "folder-{{.Namespace}}",
+	}
+	resp, err := server.GetMetrics(context.Background(), &protos.GetMetricsRequest{
+		MetricName:      "queue",
+		ScaledObjectRef: &protos.ScaledObjectRef{Name: "worker", Namespace: "jobs", ScalerMetadata: metadata},
+	})
+	if err != nil {
+		t.Fatalf("GetMetrics() error = %v", err)
+	}
+	if got := resp.MetricValues[0].MetricValueFloat; got != 7 {
+		t.Fatalf("GetMetrics() = %v, want 7", got)
+	}
+	if req := backend.Requests()[0]; req.FolderID != "folder-jobs" {
+		t.Fatalf("folder = %q, want folder-jobs", req.FolderID)
+	}
+	if metadata["query"] != `"queue"{namespace="{{.Namespace}}"}` {
+		t.Fatalf("GetMetrics() modified the request metadata: %q", metadata["query"])
+	}
+}
@@ -0,0 +1,153 @@
+package telemetry
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+type Labels map[string]string
+
+type metricType string
+
+const (
+	typeGauge   metricType = "gauge"
+	typeCounter metricType = "counter"
+)
+
+type family struct {
+	help    string
+	kind    metricType
+	samples map[string]float64 // keyed by the rendered label set
+}
+
+// Registry holds gauges and counters and serves them in the Prometheus text
+// exposition format.
+type Registry struct {
+	mu       sync.Mutex
+	families map[string]*family
+}
+
+func NewRegistry() *Registry {
+	return &Registry{families: map[string]*family{}}
+}
+
+func (r *Registry) SetGauge(name, help string, labels Labels, value float64) {
+	r.update(name, help, typeGauge, labels, func(float64) float64 { return value })
+}
+
+func (r *Registry) AddCounter(name, help string, labels Labels, delta float64) {
+	r.update(name, help, typeCounter, labels, func(old float64) float64 { return old + delta })
+}
+
+// Value returns the current value of a sample, mainly for tests.
+func (r *Registry) Value(name string, labels Labels) (float64, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	f, ok := r.families[name]
+	if !ok {
+		return 0, false
+	}
+	v, ok := f.samples[renderLabels(labels)]
+	return v, ok
+}
+
+// Delete removes the sample with the given labels, e.g. once the object it
+// describes is gone.
+func (r *Registry) Delete(name string, labels Labels) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	f, ok := r.families[name]
+	if !ok {
+		return
+	}
+	delete(f.samples, renderLabels(labels))
+	if len(f.samples) == 0 {
+		delete(r.families, name)
+	}
+}
+
+func (r *Registry) update(name, help string, kind metricType, labels Labels, fn func(float64) float64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	f, ok := r.families[name]
+	if !ok {
+		f = &family{help: help, kind: kind, samples: map[string]float64{}}
+		r.families[name] = f
+	}
+	key := renderLabels(labels)
+	f.samples[key] = fn(f.samples[key])
+}
+
+func (r *Registry) WriteTo(w io.Writer) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	names := make([]string, 0, len(r.families))
+	for name := range r.families {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	for _, name := range names {
+		f := r.families[name]
+		fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s %s\n", name, f.help, name, f.kind)
+
+		keys := make([]string, 0, len(f.samples))
+		for key := range f.samples {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			fmt.Fprintf(&b, "%s%s %s\n", name, key, formatValue(f.samples[key]))
+		}
+	}
+
+	n, err := io.WriteString(w, b.String())
+	return int64(n), err
+}
+
+func (r *Registry) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	r.WriteTo(w)
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func renderLabels(labels Labels) string {
+	if len(labels) == 0 {
+		return ""
+	}
+	names := make([]string, 0, len(labels))
+	for name := range labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	parts := make([]string, len(names))
+	for i, name := range names {
+		parts[i] = name + `="` + labelEscaper.Replace(labels[name]) + `"`
+	}
+	return "{" + strings.Join(parts, ",") + "}"
+}
+
+func formatValue(v float64) string {
+	switch {
+	case math.IsNaN(v):
+		return "NaN"
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
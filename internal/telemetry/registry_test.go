@@ -0,0 +1,63 @@
+package telemetry
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestRegistryWritesTextFormat(t *testing.T) {
+	r := NewRegistry()
+	r.SetGauge("scaler_value", "Current value.", Labels{"name": "b", "namespace": "default"}, 2)
+	r.SetGauge("scaler_value", "Current value.", Labels{"name": "a", "namespace": "default"}, 1.5)
+	r.SetGauge("scaler_value", "Current value.", Labels{"name": "a", "namespace": "default"}, 3)
+	r.AddCounter("scaler_errors_total", "Errors.", nil, 1)
+	r.AddCounter("scaler_errors_total", "Errors.", nil, 1)
+	r.SetGauge("scaler_ratio", "Ratio.", Labels{"q": `say "hi"`}, math.NaN())
+
+	var b strings.Builder
+	if _, err := r.WriteTo(&b); err != nil {
+		t.Fatalf("WriteTo: %v", err)
+	}
+
+	want := `# HELP scaler_errors_total Errors.
+# TYPE scaler_errors_total counter
+scaler_errors_total 2
+# HELP scaler_ratio Ratio.
+# TYPE scaler_ratio gauge
+scaler_ratio{q="say \"hi\""} NaN
+# HELP scaler_value Current value.
+# TYPE scaler_value gauge
+scaler_value{name="a",namespace="default"} 3
+scaler_value{name="b",namespace="default"} 2
+`
+	if b.String() != want {
+		t.Errorf("got:\n%s\nwant:\n%s", b.String(), want)
+	}
+
+	if v, ok := r.Value("scaler_value", Labels{"namespace": "default", "name": "a"}); !ok || v != 3 {
+		t.Errorf("Value = %v, %v", v, ok)
+	}
+}
+
+func TestRegistryDelete(t *testing.T) {
+	r := NewRegistry()
+	r.SetGauge("scaler_value", "Current value.", Labels{"name": "a"}, 1)
+	r.SetGauge("scaler_value", "Current value.", Labels{"name": "b"}, 2)
+
+	r.Delete("scaler_value", Labels{"name": "a"})
+	r.Delete("missing", Labels{"name": "a"})
+	if _, ok := r.Value("scaler_value", Labels{"name": "a"}); ok {
+		t.Error("deleted sample is still present")
+	}
+	if v, ok := r.Value("scaler_value", Labels{"name": "b"}); !ok || v != 2 {
+		t.Errorf("other sample = %v, %v", v, ok)
+	}
+
+	r.Delete("scaler_value", Labels{"name": "b"})
+	var b strings.Builder
+	r.WriteTo(&b)
+	if b.String() != "" {
+		t.Errorf("registry without samples writes %q", b.String())
+	}
+}